@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+var ErrAccountInactive = errors.New("account is deactivated or no longer exists")
+
+// checkAccount memastikan akun milik principal masih ada dan aktif, sehingga
+// akun yang dinonaktifkan, ditangguhkan atau dihapus langsung kehilangan akses
+// walau tokennya belum kedaluwarsa. User yang dihapus tersaring oleh soft delete.
+func checkAccount(tx *gorm.DB, role string, subjectID uint) error {
+	var model interface{}
+	switch role {
+	case RoleUser:
+		model = &models.User{}
+	case RoleOperator:
+		model = &models.Operator{}
+	case RoleAdmin:
+		model = &models.Admin{}
+	default:
+		return ErrUnknownRole
+	}
+
+	query := tx.Model(model).Where("id = ?", subjectID)
+	if role == RoleUser {
+		query = query.Where("suspended_at IS NULL")
+	} else {
+		query = query.Where("active = ?", true)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAccountInactive
+	}
+	return nil
+}
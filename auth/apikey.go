@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader adalah header tempat integrasi mesin mengirim API key
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix menandai string sebagai API key milik aplikasi ini
+const apiKeyPrefix = "sk_"
+
+// lastUsedInterval membatasi seberapa sering LastUsedAt ditulis ke database
+const lastUsedInterval = time.Minute
+
+const apiKeyPrincipalKey = "apiKeyPrincipal"
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid, revoked or expired API key")
+	ErrAPIKeyRateLimited = errors.New("API key rate limit exceeded")
+)
+
+// NewAPIKey membuat API key baru. Mengembalikan key asli (ditampilkan sekali ke
+// admin), prefix untuk identifikasi di daftar key, dan hash untuk disimpan.
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + token
+	return key, key[:len(apiKeyPrefix)+8], HashToken(key), nil
+}
+
+// AcceptAPIKeys mengautentikasi request yang membawa header X-API-Key sebagai
+// service principal. Principal ini sengaja tidak ditaruh di tempat yang dibaca
+// PrincipalFrom: ia baru terlihat oleh handler setelah RequirePermission
+// memastikan key punya scope untuk route tersebut, sehingga route tanpa
+// permission (password, sesi, MFA) tetap tertutup untuk API key.
+// Request tanpa header diteruskan ke RequireRole seperti biasa.
+func AcceptAPIKeys(c *fiber.Ctx) error {
+	presented := c.Get(APIKeyHeader)
+	if presented == "" {
+		return c.Next()
+	}
+
+	var key models.APIKey
+	err := db.DB.Preload("Permissions").Where("key_hash = ?", HashToken(presented)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !key.Active(time.Now())) {
+		return unauthenticated(c, ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+			Message: "cannot load API key",
+			Error:   err.Error(),
+		})
+	}
+
+	if retryAfter, ok := apiKeyLimiter.allow(key.ID, key.RateLimit, time.Now()); !ok {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+		return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+			Message: "Too many requests",
+			Error:   ErrAPIKeyRateLimited.Error(),
+		})
+	}
+
+	touchAPIKey(&key, c.IP())
+
+	perms := map[string]bool{}
+	for _, perm := range key.Permissions {
+		perms[perm.Name] = true
+	}
+	c.Locals(apiKeyPrincipalKey, &Principal{
+		Role:        RoleService,
+		SubjectID:   key.ID,
+		Source:      SourceAPIKey,
+		permissions: perms,
+	})
+	return c.Next()
+}
+
+// APIKeyPrincipalFrom mengambil service principal yang di-set oleh AcceptAPIKeys
+func APIKeyPrincipalFrom(c *fiber.Ctx) (*Principal, bool) {
+	principal, ok := c.Locals(apiKeyPrincipalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// touchAPIKey mencatat waktu dan IP pemakaian terakhir key
+func touchAPIKey(key *models.APIKey, ip string) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < lastUsedInterval && key.LastUsedIP == ip {
+		return
+	}
+	db.DB.Model(key).Updates(map[string]interface{}{
+		"last_used_at": now,
+		"last_used_ip": ip,
+	})
+}
+
+// rateLimiter membatasi request per API key dengan jendela tetap satu menit.
+// Hitungan disimpan di memori per instance aplikasi.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[uint]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+var apiKeyLimiter = &rateLimiter{windows: map[uint]*rateWindow{}}
+
+// allow mencatat satu request untuk key dan mengembalikan false beserta sisa
+// waktu jendela jika batas per menit sudah tercapai
+func (l *rateLimiter) allow(keyID uint, limit int, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window, ok := l.windows[keyID]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		l.windows[keyID] = window
+	}
+	if limit > 0 && window.count >= limit {
+		return window.start.Add(time.Minute).Sub(now), false
+	}
+	window.count++
+	return 0, true
+}
@@ -0,0 +1,267 @@
+// Package auth menangani penerbitan dan validasi token JWT untuk ketiga role
+// (user, operator, admin) serta middleware yang menaruh principal ke c.Locals.
+package auth
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+const (
+	RoleUser     = "user"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+	// RoleService adalah principal integrasi mesin yang masuk lewat API key
+	RoleService = "service"
+)
+
+// AccessTokenTTL adalah masa berlaku token akses. Dibuat pendek karena
+// sesi diperpanjang lewat refresh token.
+const AccessTokenTTL = 15 * time.Minute
+
+const principalKey = "principal"
+
+// Sumber kredensial sebuah request. Cookie dipakai browser, Bearer dipakai
+// aplikasi mobile dan klien server.
+const (
+	SourceCookie = "cookie"
+	SourceBearer = "bearer"
+	SourceAPIKey = "api_key"
+)
+
+// Principal adalah identitas pemanggil yang sudah terautentikasi
+type Principal struct {
+	Role      string
+	SubjectID uint
+	SessionID uint
+	// Source adalah asal kredensial (SourceCookie, SourceBearer atau SourceAPIKey)
+	Source string
+
+	permissions map[string]bool
+}
+
+// Claims adalah claim token akses: subject standar ditambah ID sesi
+type Claims struct {
+	SessionID uint `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Subject mengembalikan ID principal dalam bentuk string, sama seperti claim "sub".
+// Service principal diberi awalan "apikey:" agar tidak tertukar dengan ID operator
+// pada kolom seperti ProductIn.OperatorID.
+func (p *Principal) Subject() string {
+	id := strconv.FormatUint(uint64(p.SubjectID), 10)
+	if p.Role == RoleService {
+		return "apikey:" + id
+	}
+	return id
+}
+
+// roleConfig menyimpan nama cookie dan flag Secure cookie per role.
+// Cookie refresh token hanya dikirim ke prefix route milik role tersebut.
+type roleConfig struct {
+	Cookie        string
+	RefreshCookie string
+	RefreshPath   string
+	CSRFCookie    string
+	Secure        bool
+}
+
+var roles = map[string]roleConfig{
+	RoleUser:     {Cookie: "jwt", RefreshCookie: "jwt_refresh", RefreshPath: "/api", CSRFCookie: "csrf_token", Secure: true},
+	RoleOperator: {Cookie: "jwt_operator", RefreshCookie: "jwt_operator_refresh", RefreshPath: "/operator", CSRFCookie: "csrf_token_operator"},
+	RoleAdmin:    {Cookie: "jwt_admin", RefreshCookie: "jwt_admin_refresh", RefreshPath: "/admin", CSRFCookie: "csrf_token_admin"},
+}
+
+var (
+	ErrUnknownRole  = errors.New("unknown role")
+	ErrMissingToken = errors.New("no JWT token found")
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// tokenIssuer adalah claim "iss" semua token, diambil dari APP_BASE_URL saat
+// dipakai karena .env baru dimuat di main
+func tokenIssuer() string {
+	issuer := os.Getenv("APP_BASE_URL")
+	if issuer == "" {
+		issuer = "http://localhost:8080"
+	}
+	return strings.TrimRight(issuer, "/")
+}
+
+// registeredClaims membentuk claim standar. Audience membedakan kegunaan token:
+// role untuk token akses, "<role>:mfa" untuk tantangan MFA, dan seterusnya.
+func registeredClaims(audience string, subject string, expiresAt time.Time) jwt.RegisteredClaims {
+	now := time.Now()
+	return jwt.RegisteredClaims{
+		Issuer:    tokenIssuer(),
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+}
+
+// verifiableClaims adalah claim yang membawa jwt.RegisteredClaims
+type verifiableClaims interface {
+	jwt.Claims
+	VerifyAudience(cmp string, req bool) bool
+	VerifyIssuer(cmp string, req bool) bool
+}
+
+// parseClaims memvalidasi tanda tangan token terhadap kunci di keyring, lalu
+// issuer dan audience-nya
+func parseClaims(tokenString string, audience string, claims verifiableClaims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, verificationKey, validMethods)
+	if err != nil || !token.Valid {
+		return ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(tokenIssuer(), true) || !claims.VerifyAudience(audience, true) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// IssueToken menandatangani token akses untuk subject dengan role tertentu dalam sesi sessionID
+func IssueToken(role string, subjectID uint, sessionID uint) (string, time.Time, error) {
+	if _, ok := roles[role]; !ok {
+		return "", time.Time{}, ErrUnknownRole
+	}
+
+	expiresAt := time.Now().Add(AccessTokenTTL)
+	signed, err := signClaims(Claims{
+		SessionID:        sessionID,
+		RegisteredClaims: registeredClaims(role, strconv.FormatUint(uint64(subjectID), 10), expiresAt),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ParseToken memvalidasi token untuk role tertentu dan mengembalikan principal-nya
+func ParseToken(role string, tokenString string) (*Principal, error) {
+	claims := &Claims{}
+	if err := parseClaims(tokenString, role, claims); err != nil {
+		return nil, err
+	}
+
+	subjectID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || subjectID == 0 || claims.SessionID == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	return &Principal{Role: role, SubjectID: uint(subjectID), SessionID: claims.SessionID}, nil
+}
+
+// SetTokenCookie menaruh token akses ke cookie milik role
+func SetTokenCookie(c *fiber.Ctx, role string, token string, expires time.Time) {
+	cfg := roles[role]
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.Cookie,
+		Value:    token,
+		Expires:  expires,
+		HTTPOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: "Lax",
+		Path:     "/",
+	})
+}
+
+// ClearTokenCookie menghapus cookie token milik role
+func ClearTokenCookie(c *fiber.Ctx, role string) {
+	cfg := roles[role]
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.Cookie,
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: "Lax",
+		Path:     "/",
+	})
+}
+
+// bearerToken mengambil token dari header "Authorization: Bearer <token>"
+func bearerToken(c *fiber.Ctx) string {
+	header := c.Get(fiber.HeaderAuthorization)
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// RequireRole memvalidasi token dari header Authorization: Bearer atau, jika
+// tidak ada, dari cookie role satu kali per request, memastikan sesinya belum
+// dicabut, lalu menaruh principal ke c.Locals. Request non-GET yang memakai
+// cookie juga harus membawa token CSRF sesi. Jika principal sudah ada dengan
+// role lain, request ditolak.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if principal, ok := PrincipalFrom(c); ok {
+			if principal.Role != role {
+				return forbidden(c, "This endpoint requires the "+role+" role")
+			}
+			return c.Next()
+		}
+
+		// API key sudah diautentikasi oleh AcceptAPIKeys; aksesnya diputuskan RequirePermission
+		if _, ok := APIKeyPrincipalFrom(c); ok {
+			return c.Next()
+		}
+
+		// Header Bearer diutamakan agar klien yang juga membawa cookie lama tetap konsisten
+		source, token := SourceBearer, bearerToken(c)
+		if token == "" {
+			source, token = SourceCookie, c.Cookies(roles[role].Cookie)
+		}
+		if token == "" {
+			return unauthenticated(c, ErrMissingToken)
+		}
+
+		principal, err := ParseToken(role, token)
+		if err != nil {
+			return unauthenticated(c, err)
+		}
+		principal.Source = source
+
+		session, err := touchSession(principal)
+		if err != nil {
+			return unauthenticated(c, err)
+		}
+
+		if err := verifyCSRF(c, principal, session); err != nil {
+			return forbidden(c, err.Error())
+		}
+
+		c.Locals(principalKey, principal)
+		return c.Next()
+	}
+}
+
+// PrincipalFrom mengambil principal yang di-set oleh RequireRole
+func PrincipalFrom(c *fiber.Ctx) (*Principal, bool) {
+	principal, ok := c.Locals(principalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+func unauthenticated(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+		Message: "unauthenticated",
+		Error:   err.Error(),
+	})
+}
+
+func forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+		Message: "forbidden",
+		Error:   message,
+	})
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// CSRFHeader adalah header tempat frontend mengirim ulang token CSRF
+const CSRFHeader = "X-CSRF-Token"
+
+var ErrInvalidCSRFToken = errors.New("missing or invalid CSRF token")
+
+// newCSRFToken membuat token CSRF (synchronizer token) untuk satu sesi. Hanya
+// hash-nya yang disimpan di tabel sessions.
+func newCSRFToken() (string, string, error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// csrfSafeMethod menandakan method yang tidak mengubah state dan tidak perlu dicek
+func csrfSafeMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// verifyCSRF memastikan request yang diautentikasi lewat cookie membawa token
+// CSRF milik sesinya di header X-CSRF-Token. Klien Bearer tidak dicek karena
+// browser tidak pernah mengirim header Authorization secara otomatis.
+func verifyCSRF(c *fiber.Ctx, principal *Principal, session *models.Session) error {
+	if principal.Source != SourceCookie || csrfSafeMethod(c.Method()) {
+		return nil
+	}
+
+	presented := c.Get(CSRFHeader)
+	if presented == "" || session.CSRFTokenHash == "" {
+		return ErrInvalidCSRFToken
+	}
+	if subtle.ConstantTimeCompare([]byte(HashToken(presented)), []byte(session.CSRFTokenHash)) != 1 {
+		return ErrInvalidCSRFToken
+	}
+	return nil
+}
+
+// setCSRFCookie menaruh token CSRF ke cookie yang bisa dibaca JavaScript
+// (bukan HttpOnly) supaya frontend di origin yang sama bisa menyalinnya ke header.
+func setCSRFCookie(c *fiber.Ctx, role string, token string, expires time.Time) {
+	cfg := roles[role]
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.CSRFCookie,
+		Value:    token,
+		Expires:  expires,
+		Secure:   cfg.Secure,
+		SameSite: "Lax",
+		Path:     "/",
+	})
+}
+
+func clearCSRFCookie(c *fiber.Ctx, role string) {
+	setCSRFCookie(c, role, "", time.Now().Add(-time.Hour))
+}
@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK adalah kunci publik dalam format RFC 7517 (RSA) dan RFC 8037 (OKP/Ed25519)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+// JWKS adalah dokumen /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWKS mengembalikan semua kunci publik yang masih dipakai untuk verifikasi,
+// termasuk kunci berikutnya yang sudah dipublikasikan tetapi belum aktif
+func PublicJWKS() (*JWKS, error) {
+	keys, err := signingKeys.load(false)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range keys {
+		jwk := JWK{KeyID: key.record.KeyID, Use: "sig", Algorithm: key.record.Algorithm}
+		switch public := key.public.(type) {
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
@@ -0,0 +1,428 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// Jadwal rotasi kunci penandatangan JWT
+const (
+	// defaultSigningKeyLifetime adalah lama satu kunci dipakai menandatangani
+	// (bisa diubah lewat env JWT_KEY_ROTATION_DAYS)
+	defaultSigningKeyLifetime = 30 * 24 * time.Hour
+	// signingKeyPrepublish: kunci berikutnya dibuat dan dipublikasikan di JWKS
+	// sejauh ini sebelum aktif, agar layanan lain sempat meng-cache-nya
+	signingKeyPrepublish = 24 * time.Hour
+	// VerificationGrace adalah lama kunci lama masih diterima setelah diganti.
+	// Harus lebih panjang dari TTL token terpanjang (AccessTokenTTL, OIDCStateTTL).
+	VerificationGrace = time.Hour
+	// keyringRefresh adalah umur cache kunci di memori sebelum dibaca ulang dari database
+	keyringRefresh = time.Minute
+)
+
+// Algoritma yang didukung; pilih lewat env JWT_SIGNING_ALG (default EdDSA)
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+var (
+	ErrNoSigningKey  = errors.New("no active signing key")
+	ErrUnknownKeyID  = errors.New("unknown signing key id")
+	errKeyEncryption = errors.New("JWT_KEY_ENCRYPTION_KEY must be 32 bytes encoded in base64")
+	// ErrMissingKeyEncryptionKey mencegah private key tersimpan tanpa enkripsi di luar development
+	ErrMissingKeyEncryptionKey = errors.New("JWT_KEY_ENCRYPTION_KEY is not set; private signing keys would be stored unencrypted (only allowed with " + models.EnvironmentEnv + "=development)")
+)
+
+var plaintextKeyWarning sync.Once
+
+// signingKey adalah SigningKey yang sudah di-decode beserta masa berlakunya
+type signingKey struct {
+	record  models.SigningKey
+	private crypto.Signer
+	public  crypto.PublicKey
+	method  jwt.SigningMethod
+	// expiresAt adalah batas verifikasi; nil selama kunci belum digantikan
+	expiresAt *time.Time
+}
+
+// verifiable menandakan token yang ditandatangani kunci ini masih boleh diterima
+func (k *signingKey) verifiable(now time.Time) bool {
+	return k.record.RevokedAt == nil && (k.expiresAt == nil || now.Before(*k.expiresAt))
+}
+
+type keyring struct {
+	mu       sync.Mutex
+	keys     []*signingKey // urut berdasarkan NotBefore
+	loadedAt time.Time
+}
+
+var signingKeys = &keyring{}
+
+// signingKeyLifetime membaca JWT_KEY_ROTATION_DAYS
+func signingKeyLifetime() time.Duration {
+	if days, err := strconv.Atoi(os.Getenv("JWT_KEY_ROTATION_DAYS")); err == nil && days > 0 {
+		return time.Duration(days) * 24 * time.Hour
+	}
+	return defaultSigningKeyLifetime
+}
+
+// load membaca ulang kunci dari database jika cache sudah tua atau force
+func (r *keyring) load(force bool) ([]*signingKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !force && r.keys != nil && time.Since(r.loadedAt) < keyringRefresh {
+		return r.keys, nil
+	}
+
+	var records []models.SigningKey
+	if err := db.DB.Where("revoked_at IS NULL").Order("not_before").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]*signingKey, 0, len(records))
+	for _, record := range records {
+		key, err := decodeSigningKey(record)
+		if err != nil {
+			log.Printf("Skipping signing key %s: %v\n", record.KeyID, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].record.NotBefore.Before(keys[j].record.NotBefore) })
+
+	// Kunci yang sudah digantikan kunci aktif berikutnya hanya diverifikasi selama VerificationGrace
+	now := time.Now()
+	for i := 0; i+1 < len(keys); i++ {
+		if next := keys[i+1].record.NotBefore; !next.After(now) {
+			expiresAt := next.Add(VerificationGrace)
+			keys[i].expiresAt = &expiresAt
+		}
+	}
+
+	r.keys, r.loadedAt = keys, now
+	return keys, nil
+}
+
+// current mengembalikan kunci untuk menandatangani: NotBefore terbaru yang sudah lewat
+func (r *keyring) current() (*signingKey, error) {
+	keys, err := r.load(false)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := len(keys) - 1; i >= 0; i-- {
+		if !keys[i].record.NotBefore.After(now) {
+			return keys[i], nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// lookup mencari kunci verifikasi berdasarkan kid; cache dibaca ulang sekali
+// jika kid belum dikenal, misalnya kunci baru dibuat oleh instance lain
+func (r *keyring) lookup(keyID string) (*signingKey, error) {
+	for _, force := range []bool{false, true} {
+		keys, err := r.load(force)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if key.record.KeyID == keyID {
+				if !key.verifiable(time.Now()) {
+					return nil, ErrInvalidToken
+				}
+				return key, nil
+			}
+		}
+		r.mu.Lock()
+		recent := time.Since(r.loadedAt) < time.Second
+		r.mu.Unlock()
+		if recent {
+			break
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// signClaims menandatangani claims dengan kunci aktif dan kid-nya. Jika belum
+// ada kunci sama sekali (database baru), kunci pertama dibuat saat itu juga.
+func signClaims(claims jwt.Claims) (string, error) {
+	key, err := signingKeys.current()
+	if errors.Is(err, ErrNoSigningKey) {
+		if err = RotateSigningKeys(); err == nil {
+			key, err = signingKeys.current()
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.record.KeyID
+	return token.SignedString(key.private)
+}
+
+// verificationKey adalah jwt.Keyfunc untuk semua token yang diterbitkan aplikasi
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	keyID, ok := token.Header["kid"].(string)
+	if !ok || keyID == "" {
+		return nil, ErrInvalidToken
+	}
+	key, err := signingKeys.lookup(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, ErrInvalidToken
+	}
+	return key.public, nil
+}
+
+// validMethods adalah algoritma yang diterima saat memvalidasi token
+var validMethods = jwt.WithValidMethods([]string{AlgEdDSA, AlgRS256})
+
+// RotateSigningKeys memastikan ada kunci aktif, membuat kunci berikutnya saat
+// jadwal rotasinya sudah dekat, dan menghapus kunci yang masa verifikasinya habis.
+// Aman dijalankan bersamaan di beberapa instance: NotBefore unik sehingga kunci
+// terjadwal yang sama hanya tersimpan satu kali.
+func RotateSigningKeys() error {
+	keys, err := signingKeys.load(true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	lifetime := signingKeyLifetime()
+	var notBefore time.Time
+	if len(keys) == 0 {
+		notBefore = now.Truncate(time.Second)
+	} else if latest := keys[len(keys)-1].record.NotBefore; !now.Before(latest.Add(lifetime - signingKeyPrepublish)) {
+		notBefore = latest.Add(lifetime)
+		if notBefore.Before(now) {
+			notBefore = now.Truncate(time.Second)
+		}
+	}
+
+	if !notBefore.IsZero() {
+		if _, err := createSigningKey(notBefore); err != nil {
+			// Instance lain mungkin sudah membuat kunci dengan NotBefore yang sama
+			log.Printf("Could not create signing key: %v\n", err)
+		}
+	}
+
+	for _, key := range keys {
+		if key.expiresAt != nil && now.After(*key.expiresAt) {
+			db.DB.Delete(&models.SigningKey{}, key.record.ID)
+		}
+	}
+
+	_, err = signingKeys.load(true)
+	return err
+}
+
+// RotateSigningKeyNow langsung mengaktifkan kunci baru, misalnya saat kunci
+// aktif dicurigai bocor. Jika revokeCurrent, token dari kunci lama langsung ditolak.
+func RotateSigningKeyNow(revokeCurrent bool) (*models.SigningKey, error) {
+	current, err := signingKeys.current()
+	if err != nil && !errors.Is(err, ErrNoSigningKey) {
+		return nil, err
+	}
+
+	record, err := createSigningKey(time.Now().Truncate(time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if revokeCurrent && current != nil {
+		if err := db.DB.Model(&models.SigningKey{}).Where("id = ?", current.record.ID).Update("revoked_at", time.Now()).Error; err != nil {
+			return nil, err
+		}
+	}
+	_, err = signingKeys.load(true)
+	return record, err
+}
+
+// CheckKeyEncryption memastikan JWT_KEY_ENCRYPTION_KEY valid, atau boleh kosong
+// karena server berjalan di development. Dipanggil saat startup.
+func CheckKeyEncryption() error {
+	_, err := keyEncryptionKey()
+	return err
+}
+
+// StartKeyRotation menjalankan RotateSigningKeys saat startup lalu setiap jam
+func StartKeyRotation() {
+	if err := RotateSigningKeys(); err != nil {
+		log.Printf("Signing key rotation failed: %v\n", err)
+	}
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := RotateSigningKeys(); err != nil {
+				log.Printf("Signing key rotation failed: %v\n", err)
+			}
+		}
+	}()
+}
+
+// createSigningKey membuat dan menyimpan pasangan kunci baru dengan algoritma dari JWT_SIGNING_ALG
+func createSigningKey(notBefore time.Time) (*models.SigningKey, error) {
+	alg := os.Getenv("JWT_SIGNING_ALG")
+	if alg == "" {
+		alg = AlgEdDSA
+	}
+
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case AlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, errors.New("unsupported JWT_SIGNING_ALG " + alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+	sealed, encrypted, err := sealPrivateKey(privateDER)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.SigningKey{
+		KeyID:      keyID[:16],
+		Algorithm:  alg,
+		PrivateKey: sealed,
+		Encrypted:  encrypted,
+		PublicKey:  publicDER,
+		NotBefore:  notBefore,
+	}
+	if err := db.DB.Create(record).Error; err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// decodeSigningKey mem-parse kunci dari database
+func decodeSigningKey(record models.SigningKey) (*signingKey, error) {
+	privateDER := record.PrivateKey
+	if record.Encrypted {
+		var err error
+		if privateDER, err = openPrivateKey(privateDER); err != nil {
+			return nil, err
+		}
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(privateDER)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key cannot sign")
+	}
+
+	var method jwt.SigningMethod
+	switch record.Algorithm {
+	case AlgEdDSA:
+		method = jwt.SigningMethodEdDSA
+	case AlgRS256:
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, errors.New("unsupported algorithm " + record.Algorithm)
+	}
+
+	return &signingKey{record: record, private: private, public: private.Public(), method: method}, nil
+}
+
+// keyEncryptionKey membaca JWT_KEY_ENCRYPTION_KEY. Kosong hanya diterima saat
+// development, dan private key lalu disimpan tanpa enkripsi.
+func keyEncryptionKey() ([]byte, error) {
+	encoded := os.Getenv("JWT_KEY_ENCRYPTION_KEY")
+	if encoded == "" {
+		if !models.Development() {
+			return nil, ErrMissingKeyEncryptionKey
+		}
+		plaintextKeyWarning.Do(func() {
+			log.Println("WARNING: JWT_KEY_ENCRYPTION_KEY is not set; private signing keys are stored UNENCRYPTED. Never run like this outside development.")
+		})
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errKeyEncryption
+	}
+	return key, nil
+}
+
+func keyCipher() (cipher.AEAD, error) {
+	key, err := keyEncryptionKey()
+	if err != nil || key == nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealPrivateKey mengenkripsi private key dengan AES-GCM (nonce di depan ciphertext)
+func sealPrivateKey(der []byte) ([]byte, bool, error) {
+	aead, err := keyCipher()
+	if err != nil {
+		return nil, false, err
+	}
+	if aead == nil {
+		return der, false, nil
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, false, err
+	}
+	return aead.Seal(nonce, nonce, der, nil), true, nil
+}
+
+func openPrivateKey(sealed []byte) ([]byte, error) {
+	aead, err := keyCipher()
+	if err != nil {
+		return nil, err
+	}
+	if aead == nil {
+		return nil, errors.New("key is encrypted but JWT_KEY_ENCRYPTION_KEY is not set")
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted key is truncated")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
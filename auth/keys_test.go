@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+func TestKeyEncryptionKey(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	tests := []struct {
+		name    string
+		env     string
+		key     string
+		wantKey bool
+		wantErr error
+	}{
+		{"missing in production", "production", "", false, ErrMissingKeyEncryptionKey},
+		{"missing without APP_ENV", "", "", false, ErrMissingKeyEncryptionKey},
+		{"missing in development", "development", "", false, nil},
+		{"wrong length", "production", base64.StdEncoding.EncodeToString([]byte("short")), false, errKeyEncryption},
+		{"valid in production", "production", valid, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(models.EnvironmentEnv, tt.env)
+			t.Setenv("JWT_KEY_ENCRYPTION_KEY", tt.key)
+
+			key, err := keyEncryptionKey()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if (key != nil) != tt.wantKey {
+				t.Errorf("key = %v, want key: %v", key, tt.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// MFAChallengeTTL adalah waktu yang dimiliki staff untuk memasukkan kode TOTP
+// setelah password benar
+const MFAChallengeTTL = 5 * time.Minute
+
+const mfaPurpose = "mfa"
+
+// mfaClaims adalah claim token tantangan MFA. Token ini tidak membawa ID sesi
+// dan memakai audience berbeda, sehingga tidak pernah diterima sebagai token akses.
+// Counter adalah nilai mfa_challenge_counter akun saat token diterbitkan; counter
+// dinaikkan setiap login MFA berhasil sehingga token hanya bisa dipakai sekali.
+type mfaClaims struct {
+	Purpose string `json:"purpose"`
+	Counter int64  `json:"ctr"`
+	jwt.RegisteredClaims
+}
+
+func mfaAudience(role string) string {
+	return role + ":" + mfaPurpose
+}
+
+// IssueMFAChallenge menandatangani token tantangan untuk langkah kedua login
+func IssueMFAChallenge(role string, subjectID uint, counter int64) (string, time.Time, error) {
+	if _, ok := roles[role]; !ok {
+		return "", time.Time{}, ErrUnknownRole
+	}
+
+	expiresAt := time.Now().Add(MFAChallengeTTL)
+	signed, err := signClaims(mfaClaims{
+		Purpose:          mfaPurpose,
+		Counter:          counter,
+		RegisteredClaims: registeredClaims(mfaAudience(role), strconv.FormatUint(uint64(subjectID), 10), expiresAt),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// ParseMFAChallenge memvalidasi token tantangan dan mengembalikan ID subject
+// serta counter-nya
+func ParseMFAChallenge(role string, tokenString string) (uint, int64, error) {
+	claims := &mfaClaims{}
+	if err := parseClaims(tokenString, mfaAudience(role), claims); err != nil || claims.Purpose != mfaPurpose {
+		return 0, 0, ErrInvalidToken
+	}
+
+	subjectID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || subjectID == 0 {
+		return 0, 0, ErrInvalidToken
+	}
+	return uint(subjectID), claims.Counter, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// OIDCStateTTL adalah waktu yang dimiliki customer untuk menyelesaikan login di provider
+const OIDCStateTTL = 10 * time.Minute
+
+const (
+	oidcPurpose     = "oidc"
+	oidcStateCookie = "oidc_state"
+	oidcStatePath   = "/api/auth/oidc"
+)
+
+// OIDCState adalah data yang harus bertahan antara redirect ke provider dan
+// callback. Disimpan sebagai JWT bertanda tangan di cookie HttpOnly sehingga
+// server tidak perlu menyimpan state.
+type OIDCState struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	PKCEVerifier string `json:"pkce_verifier"`
+	Purpose      string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func oidcAudience() string {
+	return RoleUser + ":" + oidcPurpose
+}
+
+// SetOIDCState menandatangani state login OIDC dan menaruhnya ke cookie
+func SetOIDCState(c *fiber.Ctx, state OIDCState) error {
+	expiresAt := time.Now().Add(OIDCStateTTL)
+	state.Purpose = oidcPurpose
+	state.RegisteredClaims = registeredClaims(oidcAudience(), "", expiresAt)
+
+	signed, err := signClaims(state)
+	if err != nil {
+		return err
+	}
+
+	// SameSite Lax tetap mengirim cookie pada redirect GET dari provider ke callback
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    signed,
+		Expires:  expiresAt,
+		HTTPOnly: true,
+		Secure:   roles[RoleUser].Secure,
+		SameSite: "Lax",
+		Path:     oidcStatePath,
+	})
+	return nil
+}
+
+// TakeOIDCState membaca state login OIDC dari cookie lalu menghapus cookie-nya
+// sehingga state hanya bisa dipakai satu kali
+func TakeOIDCState(c *fiber.Ctx) (*OIDCState, error) {
+	raw := c.Cookies(oidcStateCookie)
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   roles[RoleUser].Secure,
+		SameSite: "Lax",
+		Path:     oidcStatePath,
+	})
+	if raw == "" {
+		return nil, ErrMissingToken
+	}
+
+	state := &OIDCState{}
+	if err := parseClaims(raw, oidcAudience(), state); err != nil || state.Purpose != oidcPurpose {
+		return nil, ErrInvalidToken
+	}
+	return state, nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// staffRoleTables memetakan role principal ke tabel join role staff-nya
+var staffRoleTables = map[string]struct {
+	Table  string
+	Column string
+}{
+	RoleOperator: {Table: "operator_roles", Column: "operator_id"},
+	RoleAdmin:    {Table: "admin_roles", Column: "admin_id"},
+}
+
+// Permissions mengembalikan nama permission yang dimiliki principal lewat role-nya.
+// Hasilnya di-cache pada principal selama satu request. Customer tidak punya permission;
+// permission service principal sudah diisi oleh AcceptAPIKeys dari scope key-nya.
+func Permissions(principal *Principal) (map[string]bool, error) {
+	if principal.permissions != nil {
+		return principal.permissions, nil
+	}
+
+	perms := map[string]bool{}
+	if join, ok := staffRoleTables[principal.Role]; ok {
+		var names []string
+		err := db.DB.Model(&models.Permission{}).
+			Distinct("permissions.name").
+			Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+			Joins("JOIN "+join.Table+" ON "+join.Table+".role_id = role_permissions.role_id").
+			Where(join.Table+"."+join.Column+" = ?", principal.SubjectID).
+			Pluck("permissions.name", &names).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			perms[name] = true
+		}
+	}
+
+	principal.permissions = perms
+	return perms, nil
+}
+
+// HasPermission memeriksa apakah principal memiliki permission tertentu
+func HasPermission(principal *Principal, permission string) (bool, error) {
+	perms, err := Permissions(principal)
+	if err != nil {
+		return false, err
+	}
+	return perms[permission], nil
+}
+
+// RequirePermission menolak request jika principal yang di-set oleh RequireRole
+// tidak memiliki permission yang dibutuhkan route. Service principal dari API key
+// baru ditaruh sebagai principal request setelah lolos pengecekan ini.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := PrincipalFrom(c)
+		service := false
+		if !ok {
+			principal, service = APIKeyPrincipalFrom(c)
+			if !service {
+				return unauthenticated(c, ErrMissingToken)
+			}
+		}
+
+		allowed, err := HasPermission(principal, permission)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Message: "cannot load permissions",
+				Error:   err.Error(),
+			})
+		}
+		if !allowed {
+			return forbidden(c, "This endpoint requires the "+permission+" permission")
+		}
+
+		if service {
+			c.Locals(principalKey, principal)
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenTTL adalah masa berlaku satu refresh token sebelum harus dirotasi
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrMissingRefreshToken = errors.New("no refresh token found")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+)
+
+// TokenPair adalah hasil login atau refresh
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+	// CSRFToken harus dikirim ulang di header X-CSRF-Token oleh klien cookie
+	CSRFToken string
+}
+
+// TokenModeHeader dikirim oleh klien non-browser dengan nilai "token". Pada mode
+// ini login dan refresh tidak menaruh cookie: token akses dan refresh token
+// dikembalikan di body JSON, dan refresh token dibaca dari body request.
+const TokenModeHeader = "X-Auth-Mode"
+
+// TokenMode menandakan request meminta token di body JSON alih-alih cookie
+func TokenMode(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(TokenModeHeader), "token")
+}
+
+// presentedRefreshToken mengambil refresh token dari body (mode token) atau cookie role
+func presentedRefreshToken(c *fiber.Ctx, role string) string {
+	if TokenMode(c) {
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return ""
+		}
+		return body.RefreshToken
+	}
+	return c.Cookies(roles[role].RefreshCookie)
+}
+
+// NewOpaqueToken membuat token acak yang aman untuk dikirim di URL atau cookie
+func NewOpaqueToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken mengembalikan hash SHA-256 dari token opaque untuk disimpan di database
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createRefreshToken menyimpan refresh token baru untuk sesi tertentu
+func createRefreshToken(tx *gorm.DB, role string, subjectID uint, sessionID uint) (string, *models.RefreshToken, error) {
+	token, err := NewOpaqueToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	record := &models.RefreshToken{
+		Role:      role,
+		SubjectID: subjectID,
+		SessionID: sessionID,
+		TokenHash: HashToken(token),
+		ExpiresAt: time.Now().Add(RefreshTokenTTL),
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+// issuePair menerbitkan token akses dan refresh token untuk sesi yang diberikan
+func issuePair(tx *gorm.DB, role string, subjectID uint, sessionID uint) (*TokenPair, *models.RefreshToken, error) {
+	access, accessExpiresAt, err := IssueToken(role, subjectID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh, record, err := createRefreshToken(tx, role, subjectID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: record.ExpiresAt,
+	}, record, nil
+}
+
+// StartSession dipanggil oleh handler login: memastikan akun masih aktif,
+// mencatat sesi baru, menerbitkan token akses dan refresh token, lalu menaruh
+// keduanya ke cookie kecuali request memakai mode token.
+func StartSession(c *fiber.Ctx, role string, subjectID uint) (*TokenPair, error) {
+	var pair *TokenPair
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Handler login sudah memeriksa status akun; ini jaring pengaman untuk jalur login lain
+		if err := checkAccount(tx, role, subjectID); err != nil {
+			return err
+		}
+
+		session, csrfToken, err := createSession(tx, c, role, subjectID)
+		if err != nil {
+			return err
+		}
+
+		pair, _, err = issuePair(tx, role, subjectID, session.ID)
+		if err != nil {
+			return err
+		}
+		pair.CSRFToken = csrfToken
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !TokenMode(c) {
+		setSessionCookies(c, role, pair)
+	}
+	return pair, nil
+}
+
+// RotateSession menukar refresh token dari cookie role (atau body pada mode token)
+// dengan pasangan token baru.
+// Refresh token lama dicabut; jika token yang sudah dicabut dipakai lagi, seluruh
+// sesi dicabut karena token tersebut kemungkinan dicuri.
+func RotateSession(c *fiber.Ctx, role string) (*TokenPair, error) {
+	presented := presentedRefreshToken(c, role)
+	if presented == "" {
+		return nil, ErrMissingRefreshToken
+	}
+
+	var pair *TokenPair
+	var current models.RefreshToken
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND role = ?", HashToken(presented), role).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+
+		if current.RevokedAt != nil {
+			return ErrRefreshTokenReused
+		}
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		session, err := activeSession(tx, current.SessionID, role, current.SubjectID)
+		if err != nil {
+			return err
+		}
+
+		// Refresh token dari cookie ikut terkirim otomatis oleh browser, jadi
+		// request-nya wajib membawa token CSRF sesi seperti request cookie lainnya
+		if !TokenMode(c) {
+			if err := verifyCSRF(c, &Principal{Source: SourceCookie}, session); err != nil {
+				return err
+			}
+		}
+
+		if err := checkAccount(tx, role, current.SubjectID); err != nil {
+			return err
+		}
+
+		// Pencabutan bersyarat: jika dua request me-refresh token yang sama
+		// bersamaan, hanya satu yang menang dan yang lain dianggap pemakaian ulang.
+		now := time.Now()
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		var next *models.RefreshToken
+		pair, next, err = issuePair(tx, role, current.SubjectID, session.ID)
+		if err != nil {
+			return err
+		}
+
+		// Token CSRF ikut dirotasi sehingga frontend bisa memperolehnya lagi setelah reload
+		csrfToken, csrfHash, err := newCSRFToken()
+		if err != nil {
+			return err
+		}
+		pair.CSRFToken = csrfToken
+
+		if err := tx.Model(session).Updates(map[string]interface{}{
+			"last_seen_at":    now,
+			"csrf_token_hash": csrfHash,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&current).Update("replaced_by_id", next.ID).Error
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		RevokeSession(current.SessionID)
+	}
+	if err != nil {
+		// Cookie tidak dihapus saat CSRF gagal agar situs lain tidak bisa
+		// mengeluarkan user hanya dengan memicu request refresh
+		if !TokenMode(c) && !errors.Is(err, ErrInvalidCSRFToken) {
+			ClearSessionCookies(c, role)
+		}
+		return nil, err
+	}
+
+	if !TokenMode(c) {
+		setSessionCookies(c, role, pair)
+	}
+	return pair, nil
+}
+
+// EndSession mencabut sesi yang sedang dipakai (dari principal atau refresh
+// token di cookie role / body mode token) dan menghapus cookie sesi.
+func EndSession(c *fiber.Ctx, role string) error {
+	var err error
+	if principal, ok := PrincipalFrom(c); ok && principal.Role == role {
+		err = RevokeSession(principal.SessionID)
+	} else if presented := presentedRefreshToken(c, role); presented != "" {
+		var record models.RefreshToken
+		if db.DB.Where("token_hash = ? AND role = ?", HashToken(presented), role).First(&record).Error == nil {
+			err = RevokeSession(record.SessionID)
+		}
+	}
+	ClearSessionCookies(c, role)
+	return err
+}
+
+func setSessionCookies(c *fiber.Ctx, role string, pair *TokenPair) {
+	cfg := roles[role]
+	SetTokenCookie(c, role, pair.AccessToken, pair.AccessExpiresAt)
+	setCSRFCookie(c, role, pair.CSRFToken, pair.RefreshExpiresAt)
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.RefreshCookie,
+		Value:    pair.RefreshToken,
+		Expires:  pair.RefreshExpiresAt,
+		HTTPOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: "Strict",
+		Path:     cfg.RefreshPath,
+	})
+}
+
+// ClearSessionCookies menghapus cookie token akses, refresh token dan CSRF milik role
+func ClearSessionCookies(c *fiber.Ctx, role string) {
+	cfg := roles[role]
+	ClearTokenCookie(c, role)
+	clearCSRFCookie(c, role)
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.RefreshCookie,
+		Value:    "",
+		Expires:  time.Now().Add(-time.Hour),
+		HTTPOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: "Strict",
+		Path:     cfg.RefreshPath,
+	})
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// sealedSecretPrefix menandai secret di database yang dienkripsi dengan
+// JWT_KEY_ENCRYPTION_KEY; secret tanpa prefix masih tersimpan apa adanya
+const sealedSecretPrefix = "enc:"
+
+// SealSecret mengenkripsi secret kecil (mis. secret TOTP) dengan AES-GCM dan
+// kunci yang sama seperti private key JWT. Tanpa kunci (hanya development)
+// secret dikembalikan apa adanya.
+func SealSecret(secret string) (string, error) {
+	sealed, encrypted, err := sealPrivateKey([]byte(secret))
+	if err != nil || !encrypted {
+		return secret, err
+	}
+	return sealedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenSecret membuka secret hasil SealSecret. Secret lama tanpa prefix
+// dikembalikan apa adanya.
+func OpenSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, sealedSecretPrefix) {
+		return stored, nil
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(stored, sealedSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+	secret, err := openPrivateKey(sealed)
+	return string(secret), err
+}
+
+// SealMFASecrets mengenkripsi secret TOTP staff yang masih tersimpan tanpa
+// enkripsi. Dipanggil saat startup; tidak melakukan apa pun tanpa kunci enkripsi.
+func SealMFASecrets() error {
+	for _, model := range []interface{}{&models.Operator{}, &models.Admin{}} {
+		var rows []struct {
+			ID        uint
+			MFASecret string
+		}
+		if err := db.DB.Model(model).
+			Select("id", "mfa_secret").
+			Where("mfa_secret <> '' AND mfa_secret NOT LIKE ?", sealedSecretPrefix+"%").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			sealed, err := SealSecret(row.MFASecret)
+			if err != nil {
+				return err
+			}
+			// Tanpa kunci enkripsi (development) tidak ada yang perlu diubah
+			if sealed == row.MFASecret {
+				return nil
+			}
+			// Syarat secret lama mencegah menimpa enrollment baru yang terjadi bersamaan
+			if err := db.DB.Model(model).
+				Where("id = ? AND mfa_secret = ?", row.ID, row.MFASecret).
+				Update("mfa_secret", sealed).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
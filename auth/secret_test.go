@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+func TestSealSecret(t *testing.T) {
+	t.Setenv(models.EnvironmentEnv, "production")
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+
+	const secret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	sealed, err := SealSecret(secret)
+	if err != nil {
+		t.Fatalf("SealSecret: %v", err)
+	}
+	if !strings.HasPrefix(sealed, sealedSecretPrefix) || strings.Contains(sealed, secret) {
+		t.Fatalf("secret is not sealed: %q", sealed)
+	}
+	if len(sealed) > 128 {
+		t.Errorf("sealed secret is %d characters, longer than the mfa_secret column", len(sealed))
+	}
+
+	opened, err := OpenSecret(sealed)
+	if err != nil || opened != secret {
+		t.Errorf("OpenSecret = %q, %v; want %q", opened, err, secret)
+	}
+
+	// Secret lama yang belum dienkripsi tetap bisa dibaca
+	if opened, err := OpenSecret(secret); err != nil || opened != secret {
+		t.Errorf("OpenSecret(plaintext) = %q, %v; want %q", opened, err, secret)
+	}
+
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 32))))
+	if _, err := OpenSecret(sealed); err == nil {
+		t.Error("OpenSecret succeeded with the wrong key")
+	}
+}
+
+func TestSealSecretWithoutKeyInDevelopment(t *testing.T) {
+	t.Setenv(models.EnvironmentEnv, "development")
+	t.Setenv("JWT_KEY_ENCRYPTION_KEY", "")
+
+	sealed, err := SealSecret("JBSWY3DPEHPK3PXP")
+	if err != nil || sealed != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("SealSecret without key = %q, %v; want the secret unchanged", sealed, err)
+	}
+}
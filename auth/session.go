@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// lastSeenInterval membatasi seberapa sering LastSeenAt ditulis ke database
+const lastSeenInterval = time.Minute
+
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// createSession mencatat sesi baru beserta info perangkat dari request dan
+// mengembalikan token CSRF sesi tersebut
+func createSession(tx *gorm.DB, c *fiber.Ctx, role string, subjectID uint) (*models.Session, string, error) {
+	csrfToken, csrfHash, err := newCSRFToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	session := &models.Session{
+		Role:          role,
+		SubjectID:     subjectID,
+		Device:        deviceName(c.Get("X-Device-Name"), userAgent),
+		IP:            c.IP(),
+		UserAgent:     userAgent,
+		LastSeenAt:    time.Now(),
+		CSRFTokenHash: csrfHash,
+	}
+	if err := tx.Create(session).Error; err != nil {
+		return nil, "", err
+	}
+	return session, csrfToken, nil
+}
+
+// activeSession mengambil sesi yang belum dicabut milik role dan subject tertentu
+func activeSession(tx *gorm.DB, sessionID uint, role string, subjectID uint) (*models.Session, error) {
+	var session models.Session
+	err := tx.Where("id = ? AND role = ? AND subject_id = ? AND revoked_at IS NULL", sessionID, role, subjectID).
+		First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionRevoked
+	}
+	return &session, err
+}
+
+// touchSession memastikan sesi dan akun principal masih aktif lalu memperbarui LastSeenAt
+func touchSession(principal *Principal) (*models.Session, error) {
+	session, err := activeSession(db.DB, principal.SessionID, principal.Role, principal.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkAccount(db.DB, principal.Role, principal.SubjectID); err != nil {
+		return nil, err
+	}
+
+	if time.Since(session.LastSeenAt) > lastSeenInterval {
+		db.DB.Model(session).Update("last_seen_at", time.Now())
+	}
+	return session, nil
+}
+
+// RevokeSession mencabut satu sesi beserta semua refresh token-nya
+func RevokeSession(sessionID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, "id = ?", sessionID)
+	})
+}
+
+// RevokeAllSessions mencabut semua sesi aktif milik satu akun
+func RevokeAllSessions(role string, subjectID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return RevokeAllSessionsTx(tx, role, subjectID)
+	})
+}
+
+// RevokeAllSessionsTx seperti RevokeAllSessions, tetapi di dalam transaksi tx
+// milik pemanggil sehingga pencabutan ikut gagal atau berhasil bersama perubahannya
+func RevokeAllSessionsTx(tx *gorm.DB, role string, subjectID uint) error {
+	return revokeSessions(tx, "role = ? AND subject_id = ?", role, subjectID)
+}
+
+// revokeSessions mencabut sesi yang cocok dengan kondisi beserta refresh token-nya
+func revokeSessions(tx *gorm.DB, query string, args ...interface{}) error {
+	var ids []uint
+	if err := tx.Model(&models.Session{}).Where(query, args...).Where("revoked_at IS NULL").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.Session{}).Where("id IN ?", ids).Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.RefreshToken{}).
+		Where("session_id IN ? AND revoked_at IS NULL", ids).
+		Update("revoked_at", now).Error
+}
+
+// deviceName memakai nama perangkat dari klien jika ada, jika tidak
+// menebak platform dan browser dari User-Agent.
+func deviceName(clientName string, userAgent string) string {
+	if clientName = strings.TrimSpace(clientName); clientName != "" {
+		return clientName
+	}
+
+	ua := strings.ToLower(userAgent)
+	platform := "Unknown device"
+	for _, p := range []struct{ needle, name string }{
+		{"android", "Android"},
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, p.needle) {
+			platform = p.name
+			break
+		}
+	}
+
+	for _, b := range []struct{ needle, name string }{
+		{"edg/", "Edge"},
+		{"chrome/", "Chrome"},
+		{"firefox/", "Firefox"},
+		{"safari/", "Safari"},
+		{"okhttp", "Android app"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.needle) {
+			return b.name + " on " + platform
+		}
+	}
+	return platform
+}
@@ -0,0 +1,311 @@
+package auth
+
+import (
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Attempt adalah status percobaan login gagal untuk satu kunci
+type Attempt struct {
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   *time.Time
+}
+
+// AttemptStore menyimpan penghitung login gagal. Implementasi bawaan memakai
+// database (dibagi antar instance) atau memori (satu proses, untuk pengujian).
+type AttemptStore interface {
+	Get(key string) (Attempt, error)
+	// Fail menambah penghitung; penghitung dimulai ulang jika kegagalan terakhir
+	// lebih lama dari window. lockFor dipanggil dengan jumlah kegagalan baru dan
+	// mengembalikan durasi lockout (0 berarti tidak dikunci).
+	Fail(key string, now time.Time, window time.Duration, lockFor func(failures int) time.Duration) (Attempt, error)
+	Reset(key string) error
+}
+
+// ThrottlePolicy mengatur kapan percobaan login mulai diperlambat dan dikunci
+type ThrottlePolicy struct {
+	DelayAfter    int           // kegagalan sebelum jeda progresif mulai berlaku
+	BaseDelay     time.Duration // jeda pertama, berlipat dua setiap kegagalan berikutnya
+	MaxDelay      time.Duration
+	LockAfter     int // kegagalan sebelum akun atau IP dikunci sementara
+	LockDuration  time.Duration
+	FailureWindow time.Duration // penghitung direset jika tidak ada kegagalan selama ini
+}
+
+var (
+	// AccountPolicy berlaku untuk setiap identitas login (email / operator ID / admin ID)
+	AccountPolicy = ThrottlePolicy{
+		DelayAfter:    3,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		LockAfter:     10,
+		LockDuration:  15 * time.Minute,
+		FailureWindow: time.Hour,
+	}
+	// IPPolicy lebih longgar karena banyak user bisa berbagi satu IP (NAT kantor, kampus)
+	IPPolicy = ThrottlePolicy{
+		DelayAfter:    20,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		LockAfter:     100,
+		LockDuration:  15 * time.Minute,
+		FailureWindow: time.Hour,
+	}
+)
+
+// ErrLoginThrottled dikembalikan saat percobaan login harus ditolak sementara
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// ThrottledError menyebutkan kapan login boleh dicoba lagi
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string { return ErrLoginThrottled.Error() }
+func (e *ThrottledError) Unwrap() error { return ErrLoginThrottled }
+
+// delay mengembalikan jeda wajib setelah sejumlah kegagalan
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	if failures < p.DelayAfter {
+		return 0
+	}
+	d := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.DelayAfter)))
+	if d > p.MaxDelay || d <= 0 {
+		return p.MaxDelay
+	}
+	return d
+}
+
+func (p ThrottlePolicy) lockFor(failures int) time.Duration {
+	if failures >= p.LockAfter {
+		return p.LockDuration
+	}
+	return 0
+}
+
+// retryAfter menghitung sisa waktu tunggu untuk satu kunci, 0 berarti boleh mencoba
+func (p ThrottlePolicy) retryAfter(attempt Attempt, now time.Time) time.Duration {
+	if now.Sub(attempt.LastFailureAt) > p.FailureWindow {
+		return 0
+	}
+	wait := attempt.LastFailureAt.Add(p.delay(attempt.Failures)).Sub(now)
+	if attempt.LockedUntil != nil {
+		if locked := attempt.LockedUntil.Sub(now); locked > wait {
+			wait = locked
+		}
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+var (
+	storeMu      sync.RWMutex
+	attemptStore AttemptStore
+)
+
+// Attempts mengembalikan store aktif, dipilih dari env LOGIN_ATTEMPT_STORE
+// ("memory" atau "db", default db) saat pertama kali dipakai.
+func Attempts() AttemptStore {
+	storeMu.RLock()
+	store := attemptStore
+	storeMu.RUnlock()
+	if store != nil {
+		return store
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	if attemptStore == nil {
+		if os.Getenv("LOGIN_ATTEMPT_STORE") == "memory" {
+			attemptStore = NewMemoryAttemptStore()
+		} else {
+			attemptStore = DBAttemptStore{}
+		}
+	}
+	return attemptStore
+}
+
+// SetAttemptStore mengganti store penghitung login gagal
+func SetAttemptStore(store AttemptStore) {
+	storeMu.Lock()
+	attemptStore = store
+	storeMu.Unlock()
+}
+
+// AccountKey adalah kunci penghitung untuk identitas login pada role tertentu
+func AccountKey(role string, identifier string) string {
+	return "account:" + role + ":" + strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// CheckLogin menolak percobaan login jika akun atau IP pemanggil sedang
+// diperlambat atau dikunci. Dipanggil sebelum password diperiksa.
+func CheckLogin(c *fiber.Ctx, role string, identifier string) error {
+	now := time.Now()
+	checks := []struct {
+		key    string
+		policy ThrottlePolicy
+	}{
+		{AccountKey(role, identifier), AccountPolicy},
+		{ipKey(c.IP()), IPPolicy},
+	}
+
+	var wait time.Duration
+	for _, check := range checks {
+		attempt, err := Attempts().Get(check.key)
+		if err != nil {
+			return err
+		}
+		if w := check.policy.retryAfter(attempt, now); w > wait {
+			wait = w
+		}
+	}
+
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// LoginFailed mencatat satu login gagal untuk akun dan IP pemanggil
+func LoginFailed(c *fiber.Ctx, role string, identifier string) error {
+	now := time.Now()
+	if _, err := Attempts().Fail(AccountKey(role, identifier), now, AccountPolicy.FailureWindow, AccountPolicy.lockFor); err != nil {
+		return err
+	}
+	_, err := Attempts().Fail(ipKey(c.IP()), now, IPPolicy.FailureWindow, IPPolicy.lockFor)
+	return err
+}
+
+// LoginSucceeded mereset penghitung akun. Penghitung IP sengaja tidak direset
+// agar penyerang tidak bisa menyelinginya dengan login ke akunnya sendiri.
+func LoginSucceeded(role string, identifier string) error {
+	return Attempts().Reset(AccountKey(role, identifier))
+}
+
+// UnlockAccount menghapus penghitung dan lockout milik satu identitas login.
+// Jika ip diisi, lockout IP tempat user mencoba login ikut dihapus; tanpa itu
+// user di balik IP yang terkunci tetap tidak bisa login meski akunnya dibuka.
+func UnlockAccount(role string, identifier string, ip string) error {
+	if _, ok := roles[role]; !ok {
+		return ErrUnknownRole
+	}
+	if err := Attempts().Reset(AccountKey(role, identifier)); err != nil {
+		return err
+	}
+	if ip != "" {
+		return Attempts().Reset(ipKey(ip))
+	}
+	return nil
+}
+
+// DBAttemptStore menyimpan penghitung di tabel login_attempts
+type DBAttemptStore struct{}
+
+func (DBAttemptStore) Get(key string) (Attempt, error) {
+	var record models.LoginAttempt
+	err := db.DB.Where("throttle_key = ?", key).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Attempt{}, nil
+	}
+	if err != nil {
+		return Attempt{}, err
+	}
+	return Attempt{Failures: record.Failures, LastFailureAt: record.LastFailureAt, LockedUntil: record.LockedUntil}, nil
+}
+
+func (DBAttemptStore) Fail(key string, now time.Time, window time.Duration, lockFor func(int) time.Duration) (Attempt, error) {
+	var attempt Attempt
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Pastikan baris ada agar bisa dikunci, lalu hitung ulang di bawah row lock
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.LoginAttempt{Key: key, LastFailureAt: now}).Error; err != nil {
+			return err
+		}
+
+		var record models.LoginAttempt
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("throttle_key = ?", key).Take(&record).Error; err != nil {
+			return err
+		}
+
+		if now.Sub(record.LastFailureAt) > window {
+			record.Failures = 0
+			record.LockedUntil = nil
+		}
+		record.Failures++
+		record.LastFailureAt = now
+		if d := lockFor(record.Failures); d > 0 {
+			until := now.Add(d)
+			record.LockedUntil = &until
+		}
+
+		attempt = Attempt{Failures: record.Failures, LastFailureAt: record.LastFailureAt, LockedUntil: record.LockedUntil}
+		return tx.Model(&record).Updates(map[string]interface{}{
+			"failures":        record.Failures,
+			"last_failure_at": record.LastFailureAt,
+			"locked_until":    record.LockedUntil,
+		}).Error
+	})
+	return attempt, err
+}
+
+func (DBAttemptStore) Reset(key string) error {
+	return db.DB.Where("throttle_key = ?", key).Delete(&models.LoginAttempt{}).Error
+}
+
+// MemoryAttemptStore menyimpan penghitung di memori proses
+type MemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]Attempt
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: map[string]Attempt{}}
+}
+
+func (s *MemoryAttemptStore) Get(key string) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryAttemptStore) Fail(key string, now time.Time, window time.Duration, lockFor func(int) time.Duration) (Attempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := s.attempts[key]
+	if now.Sub(attempt.LastFailureAt) > window {
+		attempt = Attempt{}
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	if d := lockFor(attempt.Failures); d > 0 {
+		until := now.Add(d)
+		attempt.LockedUntil = &until
+	}
+	s.attempts[key] = attempt
+	return attempt, nil
+}
+
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.attempts, key)
+	s.mu.Unlock()
+	return nil
+}
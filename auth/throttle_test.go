@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnlockAccountResetsAccountAndIP(t *testing.T) {
+	store := NewMemoryAttemptStore()
+	SetAttemptStore(store)
+	t.Cleanup(func() { SetAttemptStore(nil) })
+
+	const ip = "203.0.113.7"
+	account := AccountKey(RoleUser, "budi@example.com")
+	now := time.Now()
+	for i := 0; i < IPPolicy.LockAfter; i++ {
+		store.Fail(account, now, AccountPolicy.FailureWindow, AccountPolicy.lockFor)
+		store.Fail(ipKey(ip), now, IPPolicy.FailureWindow, IPPolicy.lockFor)
+	}
+	store.Fail(ipKey("198.51.100.1"), now, IPPolicy.FailureWindow, IPPolicy.lockFor)
+
+	// Tanpa IP hanya akun yang dibuka
+	if err := UnlockAccount(RoleUser, " Budi@Example.com ", ""); err != nil {
+		t.Fatal(err)
+	}
+	if attempt, _ := store.Get(account); attempt.Failures != 0 {
+		t.Errorf("account still has %d failures", attempt.Failures)
+	}
+	if attempt, _ := store.Get(ipKey(ip)); attempt.LockedUntil == nil {
+		t.Fatal("IP lockout was cleared without an IP in the request")
+	}
+
+	if err := UnlockAccount(RoleUser, "budi@example.com", ip); err != nil {
+		t.Fatal(err)
+	}
+	if attempt, _ := store.Get(ipKey(ip)); attempt.Failures != 0 || attempt.LockedUntil != nil {
+		t.Errorf("IP lockout was not cleared: %+v", attempt)
+	}
+	if attempt, _ := store.Get(ipKey("198.51.100.1")); attempt.Failures != 1 {
+		t.Errorf("unrelated IP counter was changed: %+v", attempt)
+	}
+
+	if err := UnlockAccount("customer", "budi@example.com", ip); err != ErrUnknownRole {
+		t.Errorf("unknown role error = %v, want ErrUnknownRole", err)
+	}
+}
@@ -0,0 +1,323 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+// AccountExport adalah salinan seluruh data pribadi customer
+type AccountExport struct {
+	ExportedAt time.Time                 `json:"exported_at"`
+	Profile    dto.UserResponse          `json:"profile"`
+	Cart       []dto.CartItemResponse    `json:"cart"`
+	Invoices   []dto.InvoiceResponse     `json:"invoices"`
+	Addresses  []dto.UserAddressResponse `json:"addresses"`
+	Identities []dto.IdentityResponse    `json:"linked_identities"`
+}
+
+// withDeleted dipakai pada Preload("User") di daftar invoice staff agar invoice
+// milik akun yang sudah dihapus tetap menampilkan customer (yang sudah dianonimkan)
+func withDeleted(tx *gorm.DB) *gorm.DB {
+	return tx.Unscoped()
+}
+
+// ExportAccount godoc
+// @Summary Export the authenticated user's data
+// @Description Download the profile, cart, invoices, address book and linked login providers of the authenticated user as JSON, or as a ZIP with one JSON file per section when format=zip
+// @Tags user
+// @Produce json
+// @Produce application/zip
+// @Param format query string false "json (default) or zip"
+// @Success 200 {object} AccountExport
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/user/export [get]
+func ExportAccount(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "format must be json or zip"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, principal.SubjectID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+
+	var cartItems []models.CartItem
+	if err := db.DB.Preload("Product.Category").Where("user_id = ?", principal.Subject()).Find(&cartItems).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot export account", err.Error()})
+	}
+
+	var invoices []models.Invoice
+	if err := db.DB.Preload("InvoiceItems.Product.Category").Where("user_id = ?", principal.Subject()).Order("id").Find(&invoices).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot export account", err.Error()})
+	}
+
+	var addresses []models.UserAddress
+	if err := db.DB.Where("user_id = ?", user.ID).Order("id").Find(&addresses).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot export account", err.Error()})
+	}
+
+	var identities []models.UserIdentity
+	if err := db.DB.Where("user_id = ?", user.ID).Find(&identities).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot export account", err.Error()})
+	}
+
+	export := AccountExport{
+		ExportedAt: time.Now(),
+		Profile:    dto.FromUser(&user),
+		Cart:       dto.FromCartItems(cartItems),
+		Invoices:   dto.FromInvoices(invoices, auth.RoleUser),
+		Addresses:  dto.FromUserAddresses(addresses),
+		Identities: dto.FromIdentities(identities),
+	}
+
+	if format == "json" {
+		return c.JSON(export)
+	}
+
+	archive, err := zipAccountExport(&export)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot export account", err.Error()})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="account-%d-export.zip"`, user.ID))
+	return c.Send(archive)
+}
+
+// zipAccountExport menulis setiap bagian export sebagai file JSON terpisah di dalam ZIP
+func zipAccountExport(export *AccountExport) ([]byte, error) {
+	files := []struct {
+		Name string
+		Data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"cart.json", export.Cart},
+		{"invoices.json", export.Invoices},
+		{"addresses.json", export.Addresses},
+		{"linked_identities.json", export.Identities},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DeleteAccount godoc
+// @Summary Delete the authenticated user's account
+// @Description Permanently close the account: personal data is anonymized, the cart, address book, linked login providers and pending email tokens are removed and every session is revoked. Pending invoices are cancelled and their reserved stock is released. Invoices are kept for accounting, but the recipient name, phone, street and notes of their shipping address are erased; only the region and postal code remain. Requires the current password unless the account only signs in through OIDC.
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param confirm body validators.DeleteAccountInput false "Current password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/user [delete]
+func DeleteAccount(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.DeleteAccountInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+		}
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, principal.SubjectID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+
+	// Token yang dicuri saja tidak cukup untuk menghapus akun
+	if len(user.Password) > 0 && !verifyPassword(user.Password, data.Password) {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"incorrect password", "The current password is required to delete the account"})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Invoice Pending tidak akan diproses lagi: dibatalkan dan stok reservasinya
+		// dikembalikan di transaksi yang sama dengan penghapusan akun
+		var pendingIDs []int
+		if err := tx.Model(&models.Invoice{}).Where("user_id = ? AND status = ?", principal.Subject(), models.InvoiceStatusPending).Order("id").Pluck("id", &pendingIDs).Error; err != nil {
+			return err
+		}
+		cancelled := make([]int, 0, len(pendingIDs))
+		for _, id := range pendingIDs {
+			err := releaseInvoiceStock(tx, id, models.InvoiceStatusCancelled, principal.Subject())
+			// Invoice yang baru saja disetujui operator tidak lagi Pending dan dibiarkan
+			if errors.Is(err, errInvoiceNotPending) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, id)
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":             models.DeletedUserEmail(user.ID),
+			"username":          "deleted-user",
+			"phone_number":      "",
+			"password":          nil,
+			"email_verified_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", principal.Subject()).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+
+		// Invoice tetap disimpan untuk pembukuan, tetapi snapshot alamatnya hanya
+		// menyisakan data wilayah dan kode pos
+		if err := tx.Model(&models.Invoice{}).Where("user_id = ?", principal.Subject()).Updates(map[string]interface{}{
+			"shipping_address_id":     nil,
+			"shipping_recipient_name": "",
+			"shipping_phone":          "",
+			"shipping_street":         "",
+			"shipping_notes":          "",
+		}).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.UserAddress{}, &models.UserIdentity{}, &models.EmailVerificationToken{}, &models.PasswordResetToken{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "user.delete", auth.RoleUser, uint(user.ID), fiber.Map{"cancelled_invoices": cancelled})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot delete account", err.Error()})
+	}
+
+	// Akun yang dihapus sudah ditolak checkAccount; pencabutan sesi membuat refresh token ikut mati
+	if err := auth.RevokeAllSessions(auth.RoleUser, uint(user.ID)); err != nil {
+		log.Printf("Failed to revoke sessions of deleted user %d: %v\n", user.ID, err)
+	}
+	if !auth.TokenMode(c) {
+		auth.ClearSessionCookies(c, auth.RoleUser)
+	}
+
+	return c.JSON(SuccessResponse{Message: "account deleted"})
+}
+
+// setUserSuspended menangguhkan atau memulihkan akun customer
+func setUserSuspended(c *fiber.Ctx, suspend bool) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid user ID"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+
+	action := "user.reinstate"
+	var suspendedAt *time.Time
+	if suspend {
+		action = "user.suspend"
+		now := time.Now()
+		suspendedAt = &now
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("suspended_at", suspendedAt).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, action, auth.RoleUser, uint(user.ID), fiber.Map{"suspended": suspend})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+	}
+
+	if suspend {
+		if err := auth.RevokeAllSessions(auth.RoleUser, uint(user.ID)); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot revoke sessions", err.Error()})
+		}
+	}
+
+	db.DB.First(&user, id)
+	return c.JSON(dto.FromUser(&user))
+}
+
+// SuspendUser godoc
+// @Summary Suspend a customer
+// @Description Admin-only: block a customer from logging in (password and OIDC) and revoke all of their sessions
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/suspend [put]
+func SuspendUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, true)
+}
+
+// ReinstateUser godoc
+// @Summary Reinstate a customer
+// @Description Admin-only: allow a suspended customer to log in again
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id}/reinstate [put]
+func ReinstateUser(c *fiber.Ctx) error {
+	return setUserSuspended(c, false)
+}
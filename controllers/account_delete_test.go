@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// TestDeleteAccountCancelsPendingInvoices memastikan stok yang direservasi invoice
+// Pending kembali saat pemilik akun menghapus akunnya, sedangkan invoice yang sudah
+// disetujui tidak berubah. Snapshot alamat semua invoice hanya menyisakan data wilayah.
+func TestDeleteAccountCancelsPendingInvoices(t *testing.T) {
+	conn := openTestDB(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	// Stok 3 tersisa setelah 2 unit direservasi invoice Pending
+	product := models.Product{ProductName: "Delete Account Test " + suffix, BrandName: "Test", Price: 1000, Quantity: 3, Status: true}
+	if err := conn.Create(&product).Error; err != nil {
+		t.Fatalf("cannot seed product: %v", err)
+	}
+	// Tanpa password seperti akun yang hanya login lewat OIDC, sehingga konfirmasi password dilewati
+	now := time.Now()
+	user := models.User{Email: "delete-" + suffix + "@example.test", Username: "delete", EmailVerifiedAt: &now}
+	if err := conn.Create(&user).Error; err != nil {
+		t.Fatalf("cannot seed user: %v", err)
+	}
+
+	invoice := func(status string, quantity int) models.Invoice {
+		invoice := models.Invoice{
+			UserID:     strconv.Itoa(user.ID),
+			TotalPrice: float64(quantity * product.Price),
+			CreatedAt:  now,
+			Status:     status,
+			StockHeld:  true,
+			ShippingAddress: models.Address{
+				RecipientName: "Budi",
+				Phone:         "08123456789",
+				Street:        "Jl. Mawar 1",
+				City:          "Bandung",
+				PostalCode:    "40111",
+			},
+			InvoiceItems: []models.InvoiceItem{{ProductID: product.ID, Quantity: quantity, Price: float64(product.Price), Total: float64(quantity * product.Price)}},
+		}
+		if err := conn.Create(&invoice).Error; err != nil {
+			t.Fatalf("cannot seed invoice: %v", err)
+		}
+		return invoice
+	}
+	pending := invoice(models.InvoiceStatusPending, 2)
+	approved := invoice(models.InvoiceStatusApproved, 1)
+
+	t.Cleanup(func() {
+		ids := []int{pending.ID, approved.ID}
+		conn.Where("invoice_id IN ?", ids).Delete(&models.InvoiceItem{})
+		conn.Where("id IN ?", ids).Delete(&models.Invoice{})
+		conn.Where("target_type = ? AND target_id = ?", auth.RoleUser, user.ID).Delete(&models.AuditLog{})
+		conn.Unscoped().Delete(&models.User{}, user.ID)
+		conn.Delete(&models.Product{}, product.ID)
+	})
+
+	app := fiber.New()
+	app.Delete("/api/user", withTestUser, DeleteAccount)
+	req := httptest.NewRequest(http.MethodDelete, "/api/user", nil)
+	req.Header.Set(testUserHeader, strconv.Itoa(user.ID))
+	req.Header.Set(auth.TokenModeHeader, "token")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	var reloaded models.Product
+	conn.First(&reloaded, product.ID)
+	if reloaded.Quantity != 5 {
+		t.Errorf("product quantity = %d, want 5 after releasing the pending reservation", reloaded.Quantity)
+	}
+	for _, tc := range []struct {
+		id   int
+		want string
+	}{
+		{pending.ID, models.InvoiceStatusCancelled},
+		{approved.ID, models.InvoiceStatusApproved},
+	} {
+		var got models.Invoice
+		conn.First(&got, tc.id)
+		if got.Status != tc.want {
+			t.Errorf("invoice %d status = %q, want %q", tc.id, got.Status, tc.want)
+		}
+		address := got.ShippingAddress
+		if address.RecipientName != "" || address.Phone != "" || address.Street != "" {
+			t.Errorf("invoice %d still has personal shipping data: %+v", tc.id, address)
+		}
+		if address.City != "Bandung" || address.PostalCode != "40111" {
+			t.Errorf("invoice %d lost its region data: %+v", tc.id, address)
+		}
+	}
+
+	var deleted models.User
+	conn.Unscoped().First(&deleted, user.ID)
+	if !deleted.DeletedAt.Valid || deleted.Email != models.DeletedUserEmail(user.ID) {
+		t.Errorf("user was not deleted and anonymized: %+v", deleted)
+	}
+}
@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/regions"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxAddressesPerUser membatasi ukuran buku alamat satu customer
+const maxAddressesPerUser = 20
+
+var (
+	errAddressNotFound    = errors.New("address not found")
+	errAddressBookFull    = errors.New("address book is full")
+	errNoShippingAddress  = errors.New("no shipping address")
+	errPostalCodeMismatch = errors.New("postal code does not match the selected village")
+)
+
+// resolveAddress memvalidasi wilayah input terhadap data wilayah lalu menyalin
+// input beserta nama dan kode wilayahnya ke isi alamat
+func resolveAddress(tx *gorm.DB, data *validators.AddressInput) (models.Address, error) {
+	path, err := regions.Resolve(tx, data.VillageID)
+	if err != nil {
+		return models.Address{}, err
+	}
+	if err := path.Check(data.ProvinceID, data.CityID, data.DistrictID); err != nil {
+		return models.Address{}, err
+	}
+
+	postalCode := data.PostalCode
+	if postalCode == "" {
+		postalCode = path.Village.PostalCode
+	} else if path.Village.PostalCode != "" && postalCode != path.Village.PostalCode {
+		return models.Address{}, errPostalCodeMismatch
+	}
+
+	return models.Address{
+		RecipientName: data.RecipientName,
+		Phone:         data.Phone,
+		Street:        data.Street,
+		VillageID:     path.Village.ID,
+		Village:       path.Village.Name,
+		DistrictID:    path.District.ID,
+		District:      path.District.Name,
+		CityID:        path.City.ID,
+		City:          path.City.Name,
+		ProvinceID:    path.Province.ID,
+		Province:      path.Province.Name,
+		PostalCode:    postalCode,
+		Notes:         data.Notes,
+	}, nil
+}
+
+// findUserAddress mengambil alamat milik user; alamat user lain dianggap tidak ada
+func findUserAddress(tx *gorm.DB, userID uint, id int) (*models.UserAddress, error) {
+	var address models.UserAddress
+	err := tx.Where("id = ? AND user_id = ?", id, userID).First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errAddressNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// shippingAddress memilih alamat pengiriman invoice: alamat yang diminta, atau
+// alamat default jika addressID nol
+func shippingAddress(tx *gorm.DB, userID uint, addressID uint) (*models.UserAddress, error) {
+	if addressID != 0 {
+		return findUserAddress(tx, userID, int(addressID))
+	}
+
+	var address models.UserAddress
+	err := tx.Where("user_id = ? AND is_default = ?", userID, true).First(&address).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errNoShippingAddress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// makeDefaultAddress menjadikan satu alamat default dan mencabut status default alamat lain
+func makeDefaultAddress(tx *gorm.DB, address *models.UserAddress) error {
+	if err := tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND id <> ? AND is_default = ?", address.UserID, address.ID, true).
+		Update("is_default", false).Error; err != nil {
+		return err
+	}
+	address.IsDefault = true
+	return tx.Model(address).Update("is_default", true).Error
+}
+
+// lockAddressBook mengunci baris user agar perubahan default dan batas jumlah
+// alamat tidak balapan antar request
+func lockAddressBook(tx *gorm.DB, userID uint) error {
+	var user models.User
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error
+}
+
+func addressErrorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, errAddressNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"address not found", "No address with the given ID"})
+	case errors.Is(err, regions.ErrUnknownVillage), errors.Is(err, regions.ErrRegionMismatch), errors.Is(err, errPostalCodeMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Invalid region", err.Error()})
+	case errors.Is(err, errAddressBookFull):
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{message, "An account can store at most " + strconv.Itoa(maxAddressesPerUser) + " addresses"})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{message, err.Error()})
+}
+
+// ListAddresses godoc
+// @Summary List the user's addresses
+// @Description List the authenticated user's address book, default address first
+// @Tags address
+// @Produce json
+// @Success 200 {array} dto.UserAddressResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/addresses [get]
+func ListAddresses(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var addresses []models.UserAddress
+	if err := db.DB.Where("user_id = ?", principal.SubjectID).Order("is_default desc, id").Find(&addresses).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve addresses", err.Error()})
+	}
+	return c.JSON(dto.FromUserAddresses(addresses))
+}
+
+// GetAddress godoc
+// @Summary Get an address
+// @Description Get one address from the authenticated user's address book
+// @Tags address
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} dto.UserAddressResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/addresses/{id} [get]
+func GetAddress(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid address ID"})
+	}
+
+	address, err := findUserAddress(db.DB, principal.SubjectID, id)
+	if err != nil {
+		return addressErrorResponse(c, err, "failed to retrieve address")
+	}
+	return c.JSON(dto.FromUserAddress(address))
+}
+
+// CreateAddress godoc
+// @Summary Add an address
+// @Description Add an address to the authenticated user's address book. The region is validated against /api/regions and its names are filled in from the village. The first address always becomes the default.
+// @Tags address
+// @Accept json
+// @Produce json
+// @Param address body validators.AddressInput true "Address"
+// @Success 201 {object} dto.UserAddressResponse
+// @Failure 400 {object} ErrorResponse "Validation error or unknown region"
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/addresses [post]
+func CreateAddress(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.AddressInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	resolved, err := resolveAddress(db.DB, &data)
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot create address")
+	}
+
+	address := models.UserAddress{
+		UserID:  int(principal.SubjectID),
+		Label:   data.Label,
+		Address: resolved,
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, principal.SubjectID); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.UserAddress{}).Where("user_id = ?", principal.SubjectID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxAddressesPerUser {
+			return errAddressBookFull
+		}
+
+		if err := tx.Create(&address).Error; err != nil {
+			return err
+		}
+		if data.IsDefault || count == 0 {
+			return makeDefaultAddress(tx, &address)
+		}
+		return nil
+	})
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot create address")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.FromUserAddress(&address))
+}
+
+// UpdateAddress godoc
+// @Summary Update an address
+// @Description Update an address in the authenticated user's address book. Invoices keep the address they were created with.
+// @Tags address
+// @Accept json
+// @Produce json
+// @Param id path int true "Address ID"
+// @Param address body validators.AddressInput true "Address"
+// @Success 200 {object} dto.UserAddressResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/addresses/{id} [put]
+func UpdateAddress(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid address ID"})
+	}
+
+	var data validators.AddressInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	resolved, err := resolveAddress(db.DB, &data)
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot update address")
+	}
+
+	var address *models.UserAddress
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, principal.SubjectID); err != nil {
+			return err
+		}
+
+		address, err = findUserAddress(tx, principal.SubjectID, id)
+		if err != nil {
+			return err
+		}
+
+		// is_default=false tidak mencabut status default; pilih alamat lain sebagai default
+		address.Label = data.Label
+		address.Address = resolved
+		if err := tx.Save(address).Error; err != nil {
+			return err
+		}
+		if data.IsDefault && !address.IsDefault {
+			return makeDefaultAddress(tx, address)
+		}
+		return nil
+	})
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot update address")
+	}
+
+	return c.JSON(dto.FromUserAddress(address))
+}
+
+// SetDefaultAddress godoc
+// @Summary Set the default address
+// @Description Make an address the default shipping address of the authenticated user
+// @Tags address
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} dto.UserAddressResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/addresses/{id}/default [put]
+func SetDefaultAddress(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid address ID"})
+	}
+
+	var address *models.UserAddress
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, principal.SubjectID); err != nil {
+			return err
+		}
+
+		address, err = findUserAddress(tx, principal.SubjectID, id)
+		if err != nil {
+			return err
+		}
+		return makeDefaultAddress(tx, address)
+	})
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot update address")
+	}
+
+	return c.JSON(dto.FromUserAddress(address))
+}
+
+// DeleteAddress godoc
+// @Summary Delete an address
+// @Description Remove an address from the authenticated user's address book. If it was the default, the oldest remaining address becomes the default. Invoices keep their copy of the address.
+// @Tags address
+// @Produce json
+// @Param id path int true "Address ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/addresses/{id} [delete]
+func DeleteAddress(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid address ID"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, principal.SubjectID); err != nil {
+			return err
+		}
+
+		address, err := findUserAddress(tx, principal.SubjectID, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(address).Error; err != nil {
+			return err
+		}
+		if !address.IsDefault {
+			return nil
+		}
+
+		var next models.UserAddress
+		err = tx.Where("user_id = ?", principal.SubjectID).Order("id").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return makeDefaultAddress(tx, &next)
+	})
+	if err != nil {
+		return addressErrorResponse(c, err, "Cannot delete address")
+	}
+
+	return c.JSON(SuccessResponse{Message: "address deleted"})
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+// defaultAPIKeyRateLimit dipakai jika admin tidak menentukan rate_limit
+const defaultAPIKeyRateLimit = 60
+
+// CreateAPIKeyResponse menampilkan API key asli satu kali saja
+type CreateAPIKeyResponse struct {
+	Message string             `json:"message"`
+	Key     string             `json:"key"`
+	APIKey  dto.APIKeyResponse `json:"api_key"`
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Admin-only: create an API key for a machine integration with explicit permissions, an optional expiry and a per-minute rate limit. The key is only shown in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param key body validators.APIKeyInput true "API key details"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/api-keys [post]
+func CreateAPIKey(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.APIKeyInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+	if data.ExpiresAt != nil && !data.ExpiresAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", "expires_at must be in the future"})
+	}
+	if data.RateLimit == 0 {
+		data.RateLimit = defaultAPIKeyRateLimit
+	}
+
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot create API key", err.Error()})
+	}
+
+	var apiKey models.APIKey
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		perms, err := findPermissions(tx, data.Permissions)
+		if err != nil {
+			return err
+		}
+
+		apiKey = models.APIKey{
+			Name:        data.Name,
+			Prefix:      prefix,
+			KeyHash:     hash,
+			Permissions: perms,
+			RateLimit:   data.RateLimit,
+			ExpiresAt:   data.ExpiresAt,
+			CreatedByID: principal.SubjectID,
+		}
+		if err := tx.Omit("Permissions.*").Create(&apiKey).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "apikey.create", "api_key", apiKey.ID, fiber.Map{
+			"name":        data.Name,
+			"prefix":      prefix,
+			"permissions": data.Permissions,
+			"rate_limit":  data.RateLimit,
+			"expires_at":  data.ExpiresAt,
+		})
+	})
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot create API key")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(CreateAPIKeyResponse{
+		Message: "Store this key now, it will not be shown again",
+		Key:     key,
+		APIKey:  dto.FromAPIKey(&apiKey),
+	})
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Admin-only: list API keys with their permissions, expiry and last use. Key values are never returned.
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.APIKeyResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/api-keys [get]
+func ListAPIKeys(c *fiber.Ctx) error {
+	var keys []models.APIKey
+	if err := db.DB.Preload("Permissions").Order("id desc").Find(&keys).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve API keys", err.Error()})
+	}
+	return c.JSON(dto.FromAPIKeys(keys))
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Admin-only: revoke an API key immediately
+// @Tags admin
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/api-keys/{id} [delete]
+func RevokeAPIKey(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid API key ID"})
+	}
+
+	var apiKey models.APIKey
+	if err := db.DB.First(&apiKey, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"API key not found", "No API key with the given ID"})
+	}
+	if apiKey.RevokedAt != nil {
+		return c.JSON(SuccessResponse{Message: "API key already revoked"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&apiKey).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "apikey.revoke", "api_key", apiKey.ID, fiber.Map{"name": apiKey.Name, "prefix": apiKey.Prefix})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot revoke API key", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "API key revoked"})
+}
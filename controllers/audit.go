@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// recordAudit menulis satu entri audit trail untuk perubahan yang dilakukan principal
+func recordAudit(tx *gorm.DB, principal *auth.Principal, action string, targetType string, targetID uint, details interface{}) error {
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.AuditLog{
+		ActorRole:  principal.Role,
+		ActorID:    principal.SubjectID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    string(encoded),
+	}).Error
+}
+
+// GetAuditLogs godoc
+// @Summary List audit log entries
+// @Description Admin-only: list the most recent audit log entries, optionally filtered by target
+// @Tags admin
+// @Produce json
+// @Param target_type query string false "Target type, e.g. operator, admin, user"
+// @Param target_id query int false "Target ID"
+// @Param limit query int false "Maximum number of entries (default 100, max 500)"
+// @Success 200 {array} models.AuditLog
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/audit-logs [get]
+func GetAuditLogs(c *fiber.Ctx) error {
+	query := db.DB.Order("id desc")
+
+	if targetType := c.Query("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID, err := strconv.Atoi(c.Query("target_id")); err == nil {
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	var logs []models.AuditLog
+	if err := query.Limit(limit).Find(&logs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve audit logs", err.Error()})
+	}
+
+	return c.JSON(logs)
+}
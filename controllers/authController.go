@@ -1,30 +1,30 @@
 package controllers
 
 import (
-	"os"
-	"strconv"
-	"time"
-    "fmt"
+	"errors"
+	"log"
 
-	"github.com/dgrijalva/jwt-go"
+	"github.com/go-sql-driver/mysql"
 	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
 	"github.com/raihan1405/go-restapi/models"
 	"github.com/raihan1405/go-restapi/validators"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var secretKey = os.Getenv("JWT_SECRET")
-
 // Definisikan struktur respons sukses
 type LoginResponse struct {
 	Message string `json:"message"`
 	Token   string `json:"token"`
+	Tokens  TokenResponse `json:"tokens"`
 	User    struct {
 		ID          uint   `json:"id"`
 		Username    string `json:"username"`
 		Email       string `json:"email"`
 		PhoneNumber string `json:"phoneNumber"`
+		EmailVerified bool `json:"emailVerified"`
 	} `json:"user"`
 }
 
@@ -39,62 +39,20 @@ type ErrorResponse struct {
 // @Description Get details of the authenticated user based on the JWT token
 // @Tags user
 // @Produce json
-// @Success 200 {object} models.User
+// @Success 200 {object} dto.UserResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /api/user [get]
 func GetUser(c *fiber.Ctx) error {
-    // Retrieve the JWT token from the cookies
-    cookie := c.Cookies("jwt")
-
-    // Parse the JWT token and extract the claims
-    token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-        // Use multiple keys if necessary
-        keyID, ok := token.Header["kid"].(string)
-        if !ok {
-            return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-        }
-
-        // Return the appropriate signing key based on the kid
-        switch keyID {
-        case "operator":
-            return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-        case "user":
-            return []byte(os.Getenv("JWT_SECRET")), nil
-        default:
-            return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-        }
-    })
-
-    // Handle error if token is invalid or parsing fails
-    if err != nil || !token.Valid {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-            Message: "unauthenticated",
-            Error:   "Invalid or expired token",
-        })
-    }
-
-    // Extract the claims and cast to the correct type
-    claims, ok := token.Claims.(*jwt.StandardClaims)
+    // Principal di-set oleh middleware auth.RequireRole
+    principal, ok := auth.PrincipalFrom(c)
     if !ok {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-            Message: "unauthenticated",
-            Error:   "Invalid token claims",
-        })
+        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
     }
 
-    // Convert the Subject (which is the user ID in string format) to an integer
-    userID, err := strconv.Atoi(claims.Subject)
-    if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-            Message: "Invalid token",
-            Error:   "Token contains invalid user ID",
-        })
-    }
-
-    // Retrieve the user from the database using the converted user ID
+    // Retrieve the user from the database using the principal's subject ID
     var user models.User
-    db.DB.Where("id = ?", userID).First(&user)
+    db.DB.Where("id = ?", principal.SubjectID).First(&user)
 
     // If user is not found, return a 404 error
     if user.ID == 0 {
@@ -105,7 +63,7 @@ func GetUser(c *fiber.Ctx) error {
     }
 
     // Return the user details as the response
-    return c.JSON(user)
+    return c.JSON(dto.FromUser(&user))
 }
 
 
@@ -123,38 +81,13 @@ func GetUser(c *fiber.Ctx) error {
 // @Failure 404 {object} ErrorResponse
 // @Router /api/user/password [put]
 func UpdatePassword(c *fiber.Ctx) error {
-    cookie := c.Cookies("jwt")
-    token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-        // Use multiple keys if necessary
-        keyID, ok := token.Header["kid"].(string)
-        if !ok {
-            return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-        }
-
-        // Return the appropriate signing key based on the kid
-        switch keyID {
-        case "operator":
-            return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-        case "user":
-            return []byte(os.Getenv("JWT_SECRET")), nil
-        default:
-            return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-        }
-    })
-
-    if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", err.Error()})
-    }
-    claims := token.Claims.(*jwt.StandardClaims)
-
-    // Convert claims.Subject to integer
-    userID, err := strconv.Atoi(claims.Subject)
-    if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid token", "Token contains invalid user ID"})
+    principal, ok := auth.PrincipalFrom(c)
+    if !ok {
+        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
     }
 
     var data validators.UpdatePasswordInput
-    err = c.BodyParser(&data)
+    err := c.BodyParser(&data)
     if err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
     }
@@ -165,7 +98,7 @@ func UpdatePassword(c *fiber.Ctx) error {
     }
 
     var user models.User
-    db.DB.Where("id = ?", userID).First(&user)
+    db.DB.Where("id = ?", principal.SubjectID).First(&user)
     if user.ID == 0 {
         return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
     }
@@ -196,46 +129,21 @@ func UpdatePassword(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param update body validators.UpdateUserInput true "User update details"
-// @Success 200 {object} models.User
+// @Success 200 {object} dto.UserResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
 // @Router /api/user [put]
 func UpdateProfile(c *fiber.Ctx) error {
-    cookie := c.Cookies("jwt")
-    // Parse token dengan claims
-
-    token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-        // Use multiple keys if necessary
-        keyID, ok := token.Header["kid"].(string)
-        if !ok {
-            return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-        }
-
-        // Return the appropriate signing key based on the kid
-        switch keyID {
-        case "operator":
-            return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-        case "user":
-            return []byte(os.Getenv("JWT_SECRET")), nil
-        default:
-            return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-        }
-    })
-
-    if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", err.Error()})
-    }
-    claims := token.Claims.(*jwt.StandardClaims)
-
-    // Convert claims.Subject to integer
-    userID, err := strconv.Atoi(claims.Subject)
-    if err != nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid token", "Token contains invalid user ID"})
+    principal, ok := auth.PrincipalFrom(c)
+    if !ok {
+        return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
     }
 
     var data validators.UpdateUserInput
-    err = c.BodyParser(&data)
+    err := c.BodyParser(&data)
     if err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
     }
@@ -246,21 +154,61 @@ func UpdateProfile(c *fiber.Ctx) error {
     }
 
     var user models.User
-    db.DB.Where("id = ?", userID).First(&user)
+    db.DB.Where("id = ?", principal.SubjectID).First(&user)
     if user.ID == 0 {
         return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
     }
 
+    // Email baru harus diverifikasi ulang dan belum dipakai akun lain
+    emailChanged := user.Email != data.Email
+    if emailChanged {
+        inUse, err := emailInUse(data.Email, user.ID)
+        if err != nil {
+            return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update profile", err.Error()})
+        }
+        if inUse {
+            return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The email is already in use"})
+        }
+    }
+
     user.Username = data.Username
     user.Email = data.Email
     user.PhoneNumber = data.PhoneNumber
+    if emailChanged {
+        user.EmailVerifiedAt = nil
+    }
 
-    db.DB.Save(&user)
+    if err := db.DB.Save(&user).Error; err != nil {
+        if isDuplicateKey(err) {
+            return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The email is already in use"})
+        }
+        return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update profile", err.Error()})
+    }
+
+    if emailChanged {
+        if err := sendVerificationEmail(&user); err != nil {
+            log.Printf("Failed to send verification email to user %d: %v\n", user.ID, err)
+        }
+    }
 
-    return c.JSON(user)
+    return c.JSON(dto.FromUser(&user))
 }
 
 
+// emailInUse memeriksa apakah email sudah dipakai akun lain. Akun yang dihapus
+// ikut diperiksa seperti pada index unik users.email.
+func emailInUse(email string, exceptID int) (bool, error) {
+	var count int64
+	err := db.DB.Unscoped().Model(&models.User{}).Where("email = ? AND id <> ?", email, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// isDuplicateKey menandakan query ditolak index unik (MySQL error 1062)
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
 // Register godoc
 // @Summary Register a new user
 // @Description Register a new user with the provided details
@@ -268,8 +216,9 @@ func UpdateProfile(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param register body validators.RegisterInput true "User registration details"
-// @Success 200 {object} models.User
+// @Success 200 {object} dto.UserResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/register [post]
 func Register(c *fiber.Ctx) error {
@@ -287,6 +236,15 @@ func Register(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
 	}
 
+	// Satu email hanya boleh dipakai satu akun
+	inUse, err := emailInUse(data.Email, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot create user", err.Error()})
+	}
+	if inUse {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The email is already in use"})
+	}
+
 	// Generate hashed password
 	password, err := bcrypt.GenerateFromPassword([]byte(data.Password), 14)
 	if err != nil {
@@ -302,8 +260,20 @@ func Register(c *fiber.Ctx) error {
 	}
 
 	// Save user to database
-	db.DB.Create(&user)
-	return c.JSON(user)
+	if err := db.DB.Create(&user).Error; err != nil {
+		// Pendaftaran bersamaan dengan email yang sama ditolak index unik
+		if isDuplicateKey(err) {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The email is already in use"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot create user", err.Error()})
+	}
+
+	// Kirim link verifikasi; jika gagal, user masih bisa meminta kirim ulang setelah login
+	if err := sendVerificationEmail(&user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v\n", user.ID, err)
+	}
+
+	return c.JSON(dto.FromUser(&user))
 }
 
 // Login godoc
@@ -313,28 +283,17 @@ func Register(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param login body validators.LoginInput true "User login details"
+// @Param X-Auth-Mode header string false "Set to token to receive tokens in the JSON body without cookies"
 // @Success 200 {object} LoginResponse
 // @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/login [post]
 func Login(c *fiber.Ctx) error {
 	var data validators.LoginInput
 
-    keyMap := map[string]string{
-        "user":    os.Getenv("JWT_SECRET"),
-        "operator": os.Getenv("JWT_SECRET_OPERATOR"),
-    }
-
-    secretKey, exists := keyMap["user"]
-    if !exists || secretKey == "" {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Message: "Server configuration error",
-            Error:   "JWT_SECRET is not set",
-        })
-    }
-
 	// Parse JSON data
 	err := c.BodyParser(&data)
 	if err != nil {
@@ -347,87 +306,65 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
 	}
 
-	// Find user by email
+	// Email atau IP yang terlalu sering gagal login ditolak sebelum password dicek
+	if err := auth.CheckLogin(c, auth.RoleUser, data.Email); err != nil {
+		return loginThrottleResponse(c, err)
+	}
+
+	// Find user by email and compare the password. Unknown emails and wrong
+	// passwords get the same response so accounts cannot be enumerated.
 	var user models.User
 	db.DB.Where("email = ?", data.Email).First(&user)
-
-	// Check if user exists
-	if user.ID == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"User not found", "No user with the given email"})
+	if !verifyPassword(user.Password, data.Password) {
+		recordLoginFailure(c, auth.RoleUser, data.Email)
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid credentials", "Incorrect email or password"})
 	}
 
-	// Compare password
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(data.Password))
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Incorrect password", err.Error()})
+	// Suspended accounts are blocked even with the right password
+	if user.Suspended() {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"Account suspended", "This account has been suspended"})
 	}
+	recordLoginSuccess(auth.RoleUser, data.Email)
 
-	// Generate JWT token
-	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Subject:   strconv.Itoa(int(user.ID)),
-		ExpiresAt: time.Now().Add(time.Hour * 24).Unix(), // Token expires in 24 hours
-	})
-
-    // Set the key ID for the token
-    claims.Header["kid"] = "user"
-
-	token, err := claims.SignedString([]byte(secretKey))
+	// Start a session: short-lived "jwt" access cookie plus a rotating refresh token
+	// (or both in the JSON body when the client asked for token mode)
+	pair, err := auth.StartSession(c, auth.RoleUser, uint(user.ID))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not login", err.Error()})
 	}
 
-	// Set cookie with JWT token
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    token,
-		Expires:  time.Now().Add(time.Hour * 24),
-		HTTPOnly: true,
-        Secure:   true,  // This ensures the cookie is only sent over HTTPS
-        SameSite: "Lax",
-		Path:     "/", // Allows the cookie to be sent cross-domain
-	}
-
-	c.Cookie(&cookie)
-    fmt.Printf("jwt_operator cookie set: %s\n", token)
-
 	// Return user data along with the token
 	return c.JSON(LoginResponse{
 		Message: "Login successful",
-		Token:   token,
+		Token:   pair.AccessToken,
+		Tokens:  newTokenResponse(c, pair),
 		User: struct {
 			ID          uint   `json:"id"`
 			Username    string `json:"username"`
 			Email       string `json:"email"`
 			PhoneNumber string `json:"phoneNumber"`
+			EmailVerified bool `json:"emailVerified"`
 		}{
 			ID:          uint(user.ID),
 			Username:    user.Username,
 			Email:       user.Email,
 			PhoneNumber: user.PhoneNumber,
+			EmailVerified: user.EmailVerified(),
 		},
 	})
 }
 
 // Logout godoc
 // @Summary Log out the authenticated user
-// @Description Log out the authenticated user by clearing the JWT cookie
+// @Description Log out the authenticated user by revoking the refresh token and clearing the session cookies
 // @Tags auth
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /api/logout [post]
 func LogoutUser(c *fiber.Ctx) error {
-    fmt.Println("logoutUser called")
-    
-	cookie := fiber.Cookie{
-		Name:     "jwt",
-		Value:    "",
-		Expires:  time.Now().Add(-time.Hour),
-		HTTPOnly: true,
-        SameSite: "Lax",
-		Path:     "/",
-	}
-
-	c.Cookie(&cookie)
+    if err := auth.EndSession(c, auth.RoleUser); err != nil {
+        log.Printf("Failed to revoke user refresh token: %v\n", err)
+    }
 
 	return c.JSON(map[string]interface{}{"message": "logout success"})
 
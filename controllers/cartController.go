@@ -1,15 +1,18 @@
 package controllers
 
 import (
-	"os"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4" // Menggunakan jwt dari golang-jwt/jwt/v4
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
 	"github.com/raihan1405/go-restapi/models"
 	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SuccessResponse digunakan untuk mengembalikan pesan sukses
@@ -24,26 +27,17 @@ type SuccessResponse struct {
 // @Accept json
 // @Produce json
 // @Param cart body validators.AddToCartInput true "Cart item details"
-// @Success 200 {object} models.CartItem
+// @Success 200 {object} dto.CartItemResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/cart [post]
 func AddToCart(c *fiber.Ctx) error {
-	// Verify JWT token and get user info from token
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
+	userID := principal.Subject()
 
 	// Parse request body to extract AddToCartInput data
 	var data validators.AddToCartInput
@@ -56,42 +50,58 @@ func AddToCart(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
 	}
 
-	// Check if the product exists in the database
-	var product models.Product
-	if err := db.DB.First(&product, data.ProductID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Product not found"})
-	}
+	// Lock the product row so the stock check and the cart update see the same quantity.
+	// Keranjang dikunci sebelum produk, urutan yang sama dengan checkout dan UpdateCartItem.
+	var cartItem models.CartItem
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Check if the cart item already exists
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND product_id = ?", userID, data.ProductID).
+			First(&cartItem).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
 
-	// Check if the product is in stock
-	if product.Quantity <= 0 {
-		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Error: "Product is out of stock"})
-	}
+		product, err := lockProduct(tx, data.ProductID)
+		if err != nil {
+			return err
+		}
+
+		// Check if the product has enough stock for the requested quantity
+		newQuantity := cartItem.Quantity + data.Quantity
+		if newQuantity > product.Quantity {
+			return shortStock(product, newQuantity)
+		}
 
-	// Check if the cart item already exists
-	var existingCartItem models.CartItem
-	if err := db.DB.Where("user_id = ? AND product_id = ?", userID, data.ProductID).First(&existingCartItem).Error; err == nil {
 		// If the product is already in the cart, update the quantity
-		existingCartItem.Quantity += data.Quantity
-		if err := db.DB.Save(&existingCartItem).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to update cart"})
+		if cartItem.ID != 0 {
+			cartItem.Quantity = newQuantity
+			cartItem.Product = *product
+			return tx.Omit(clause.Associations).Save(&cartItem).Error
 		}
-		return c.JSON(existingCartItem)
-	}
 
-	// Create a new cart item if not already in the cart
-	cartItem := models.CartItem{
-		ProductID: data.ProductID,
-		UserID:    userID,
-		Quantity:  data.Quantity,
-	}
+		// Create a new cart item if not already in the cart
+		cartItem = models.CartItem{
+			ProductID: data.ProductID,
+			UserID:    userID,
+			Quantity:  data.Quantity,
+			Product:   *product,
+		}
+		return tx.Omit(clause.Associations).Create(&cartItem).Error
+	})
 
-	// Save cart item to the database
-	if err := db.DB.Create(&cartItem).Error; err != nil {
+	var stockErr *insufficientStockError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Product not found"})
+	case errors.As(err, &stockErr):
+		return c.Status(fiber.StatusConflict).JSON(insufficientStockResponse(stockErr))
+	case err != nil:
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Cannot add product to cart"})
 	}
 
 	// Return the cart item response
-	return c.JSON(cartItem)
+	return c.JSON(dto.FromCartItem(&cartItem))
 }
 
 // GetCart godoc
@@ -99,39 +109,26 @@ func AddToCart(c *fiber.Ctx) error {
 // @Description Get a list of all items in the user's cart
 // @Tags cart
 // @Produce json
-// @Success 200 {array} models.CartItem
+// @Success 200 {array} dto.CartItemResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/cart [get]
 func GetCart(c *fiber.Ctx) error {
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
+	userID := principal.Subject()
 
 	var cartItems []models.CartItem
 
 	// Retrieve all cart items for the user from the database along with related product details
-	if err := db.DB.Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
+	if err := db.DB.Preload("Product.Category").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Cannot retrieve cart items"})
 	}
 
-	// Calculate TotalPrice for each cart item dynamically
-	for i := range cartItems {
-		// Calculate the total price (Quantity * Product Price)
-		cartItems[i].TotalPrice = float64(cartItems[i].Quantity * cartItems[i].Product.Price)
-	}
-
-	return c.JSON(cartItems)
+	// TotalPrice (Quantity * Product Price) dihitung oleh dto.FromCartItem
+	return c.JSON(dto.FromCartItems(cartItems))
 }
 
 // RemoveFromCart godoc
@@ -145,20 +142,12 @@ func GetCart(c *fiber.Ctx) error {
 // @Failure 500 {object} ErrorResponse
 // @Router /api/cart/{id} [delete]
 func RemoveFromCart(c *fiber.Ctx) error {
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
+	userID := principal.Subject()
 
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -186,27 +175,18 @@ func RemoveFromCart(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Cart Item ID"
 // @Param cart body validators.UpdateCartItemInput true "Updated cart item details"
-// @Success 200 {object} models.CartItem
+// @Success 200 {object} dto.CartItemResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/cart/{id} [put]
 func UpdateCartItem(c *fiber.Ctx) error {
-	// Get the JWT token and verify the user
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
+	userID := principal.Subject()
 
 	// Parse the cart item ID from the URL parameter
 	id, err := strconv.Atoi(c.Params("id"))
@@ -225,119 +205,195 @@ func UpdateCartItem(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: err.Error()})
 	}
 
-	// Retrieve the cart item from the database
+	// Retrieve the cart item and its product with row locks, then update the quantity
 	var cartItem models.CartItem
-	if err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&cartItem).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Cart item not found"})
-	}
-
-	// Check if the quantity is valid (greater than zero)
-	if data.Quantity <= 0 {
-		// Optionally, you could delete the cart item if the quantity is zero
-		if err := db.DB.Delete(&cartItem).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Cannot remove cart item"})
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&cartItem).Error; err != nil {
+			return err
 		}
-		return c.JSON(SuccessResponse{Message: "Item removed from cart"})
-	}
 
-	// Retrieve product to check stock availability
-	var product models.Product
-	if err := db.DB.First(&product, cartItem.ProductID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Product not found"})
-	}
+		product, err := lockProduct(tx, cartItem.ProductID)
+		if err != nil {
+			return err
+		}
 
-	// Check if the new quantity exceeds available stock
-	if data.Quantity > product.Quantity {
-		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Error: "Insufficient stock"})
-	}
+		// Check if the new quantity exceeds available stock
+		if data.Quantity > product.Quantity {
+			return shortStock(product, data.Quantity)
+		}
 
-	// Update the cart item quantity
-	cartItem.Quantity = data.Quantity
+		cartItem.Quantity = data.Quantity
+		cartItem.Product = *product
+		return tx.Omit(clause.Associations).Save(&cartItem).Error
+	})
 
-	// Save the updated cart item to the database
-	if err := db.DB.Save(&cartItem).Error; err != nil {
+	var stockErr *insufficientStockError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Cart item not found"})
+	case errors.As(err, &stockErr):
+		return c.Status(fiber.StatusConflict).JSON(insufficientStockResponse(stockErr))
+	case err != nil:
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Cannot update cart item"})
 	}
 
 	// Return the updated cart item
-	return c.JSON(cartItem)
+	return c.JSON(dto.FromCartItem(&cartItem))
 }
 
 // CreateInvoice godoc
 // @Summary Create an invoice from the user's cart
-// @Description Create an invoice from all selected items in the user's cart
+// @Description Create an invoice from all selected items in the user's cart. The chosen address (or the default address when address_id is omitted) is copied onto the invoice as its shipping address.
 // @Tags invoice
 // @Accept json
 // @Produce json
-// @Success 201 {object} models.Invoice
+// @Param invoice body validators.CreateInvoiceInput false "Shipping address"
+// @Success 201 {object} dto.InvoiceResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} InsufficientStockResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/invoice [post]
 func CreateInvoice(c *fiber.Ctx) error {
-	// Verify JWT token and get user info from token
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
+	userID := principal.Subject()
 
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
-
-	// Retrieve all items in the user's cart
-	var cartItems []models.CartItem
-	if err := db.DB.Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to retrieve cart items"})
+	var data validators.CreateInvoiceInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+		}
 	}
 
-	// Calculate total price of the items in the cart
-	var totalPrice float64
-	for _, item := range cartItems {
-		totalPrice += float64(item.Quantity * item.Product.Price)
+	// Alamat pengiriman wajib ada; tanpa address_id dipakai alamat default
+	address, err := shippingAddress(db.DB, principal.SubjectID, data.AddressID)
+	switch {
+	case errors.Is(err, errAddressNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"address not found", "No address with the given ID"})
+	case errors.Is(err, errNoShippingAddress):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Shipping address required", "Add an address or choose one with address_id"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to retrieve shipping address"})
 	}
 
 	// Create a new invoice
 	invoice := models.Invoice{
 		UserID:     userID,
-		TotalPrice: totalPrice,
 		CreatedAt:  time.Now(),
-		Status:     "Pending", // You can set status to "Pending" or as appropriate
-	}
+		Status:     models.InvoiceStatusPending,
+		StockHeld:  true,
+		// Snapshot alamat: perubahan buku alamat setelah ini tidak mengubah invoice
+		ShippingAddressID: &address.ID,
+		ShippingAddress:   address.Address,
+	}
+
+	// Read the cart, reserve stock, create the invoice and clear the cart in a single transaction
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Keranjang dikunci agar item yang ditambahkan selama checkout tidak ikut
+		// terhapus tanpa masuk ke invoice
+		var cartItems []models.CartItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Product").Where("user_id = ?", userID).Order("product_id").Find(&cartItems).Error; err != nil {
+			return err
+		}
 
-	// Save the invoice to the database
-	if err := db.DB.Create(&invoice).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to create invoice"})
-	}
+		if len(cartItems) == 0 {
+			return errCartEmpty
+		}
 
-	// Create invoice items for each cart item
-	for _, item := range cartItems {
-		invoiceItem := models.InvoiceItem{
-			InvoiceID: invoice.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     float64(item.Product.Price),
-			Total:     float64(item.Quantity * item.Product.Price),
+		if err := reserveStock(tx, cartItems); err != nil {
+			return err
 		}
 
-		// Save the invoice item to the database
-		if err := db.DB.Create(&invoiceItem).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to create invoice items"})
+		// Calculate total price of the items in the cart
+		cartItemIDs := make([]int, 0, len(cartItems))
+		for _, item := range cartItems {
+			invoice.TotalPrice += float64(item.Quantity * item.Product.Price)
+			cartItemIDs = append(cartItemIDs, item.ID)
 		}
-	}
 
-	// Optionally, clear the user's cart after creating the invoice
-	if err := db.DB.Where("user_id = ?", userID).Delete(&models.CartItem{}).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to clear cart after creating invoice"})
+		if err := tx.Create(&invoice).Error; err != nil {
+			return err
+		}
+
+		// Create invoice items for each cart item
+		for _, item := range cartItems {
+			invoiceItem := models.InvoiceItem{
+				InvoiceID: invoice.ID,
+				ProductID: item.ProductID,
+				Quantity:  item.Quantity,
+				Price:     float64(item.Product.Price),
+				Total:     float64(item.Quantity * item.Product.Price),
+			}
+
+			if err := tx.Create(&invoiceItem).Error; err != nil {
+				return err
+			}
+			invoiceItem.Product = item.Product
+			invoice.InvoiceItems = append(invoice.InvoiceItems, invoiceItem)
+		}
+
+		// Hanya item yang masuk ke invoice yang dihapus dari keranjang
+		return tx.Where("id IN ?", cartItemIDs).Delete(&models.CartItem{}).Error
+	})
+
+	if errors.Is(err, errCartEmpty) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "Cart is empty"})
+	}
+	var stockErr *insufficientStockError
+	if errors.As(err, &stockErr) {
+		return c.Status(fiber.StatusConflict).JSON(insufficientStockResponse(stockErr))
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to create invoice"})
 	}
 
 	// Return the created invoice
-	return c.Status(fiber.StatusCreated).JSON(invoice)
+	return c.Status(fiber.StatusCreated).JSON(dto.FromInvoice(&invoice, auth.RoleUser))
+}
+
+// CancelInvoice godoc
+// @Summary Cancel a pending invoice
+// @Description Cancel one of the logged-in user's pending invoices and release its reserved stock
+// @Tags invoice
+// @Produce json
+// @Param id path int true "Invoice ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/invoices/cancel/{id} [put]
+func CancelInvoice(c *fiber.Ctx) error {
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
+	}
+	userID := principal.Subject()
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{Error: "Invalid invoice ID"})
+	}
+
+	err = releaseInvoiceStock(db.DB, id, models.InvoiceStatusCancelled, userID)
+	switch {
+	case errors.Is(err, errInvoiceNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{Error: "Invoice not found"})
+	case errors.Is(err, errInvoiceNotPending):
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{Error: "Only pending invoices can be cancelled"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to cancel invoice"})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Invoice cancelled"})
 }
 
 // GetAllInvoices godoc
@@ -345,7 +401,7 @@ func CreateInvoice(c *fiber.Ctx) error {
 // @Description Get a list of all invoices associated with the logged-in user
 // @Tags invoice
 // @Produce json
-// @Success 200 {array} models.Invoice
+// @Success 200 {array} dto.InvoiceResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/invoices [get]
@@ -354,7 +410,7 @@ func CreateInvoice(c *fiber.Ctx) error {
 // @Description Get a list of all invoices associated with the logged-in user
 // @Tags invoice
 // @Produce json
-// @Success 200 {array} models.Invoice
+// @Success 200 {array} dto.InvoiceResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/invoices [get]
@@ -363,35 +419,26 @@ func CreateInvoice(c *fiber.Ctx) error {
 // @Description Get a list of all invoices associated with the logged-in user
 // @Tags invoice
 // @Produce json
-// @Success 200 {array} models.Invoice
+// @Success 200 {array} dto.InvoiceResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/invoices [get]
 func GetAllInvoices(c *fiber.Ctx) error {
-	// Get the JWT token and verify the user
-	user, ok := c.Locals("user").(*jwt.Token)
+	// Get the authenticated user from the principal set by auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Unauthorized"})
 	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid token claims"})
-	}
-
-	userID, ok := claims["sub"].(string)
-	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{Error: "Invalid user ID in token"})
-	}
+	userID := principal.Subject()
 
 	// Retrieve all invoices for the logged-in user, preload related InvoiceItems, Products, and User
 	var invoices []models.Invoice
-	if err := db.DB.Preload("InvoiceItems.Product").Preload("User").Where("user_id = ?", userID).Find(&invoices).Error; err != nil {
+	if err := db.DB.Preload("InvoiceItems.Product.Category").Preload("User").Where("user_id = ?", userID).Find(&invoices).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{Error: "Failed to retrieve invoices"})
 	}
 
 	// Return the list of invoices
-	return c.JSON(invoices)
+	return c.JSON(dto.FromInvoices(invoices, auth.RoleUser))
 }
 
 // GetAllInvoicesForOperator godoc
@@ -399,7 +446,7 @@ func GetAllInvoices(c *fiber.Ctx) error {
 // @Description Get a list of all invoices for all users
 // @Tags invoice
 // @Produce json
-// @Success 200 {array} models.Invoice
+// @Success 200 {array} dto.InvoiceResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/operator/invoices [get]
@@ -408,79 +455,14 @@ func GetAllInvoices(c *fiber.Ctx) error {
 // @Description Get a list of all invoices associated with all users (accessible by operator)
 // @Tags invoice
 // @Produce json
-// @Success 200 {array} models.Invoice
+// @Success 200 {array} dto.InvoiceResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/invoices [get]
 func GetAllInvoicesForOperator(c *fiber.Ctx) error {
-	// Ambil token JWT dari cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Jika tidak ada token di cookie
-	if cookie == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "No JWT token found in cookie",
-		})
-	}
-
-	// Parse token dengan claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Mengambil key ID (kid) dari header JWT
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-
-		// Return signing key berdasarkan kid
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		case "user":
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	// Cek error token atau invalid token
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid or expired token",
-		})
-	}
-
-	// Ambil claims dari token
-	claims, ok := token.Claims.(*jwt.StandardClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid token claims",
-		})
-	}
-
-	// Konversi Subject menjadi integer (Operator ID)
-	operatorID, err := strconv.Atoi(claims.Subject)
-	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "Invalid token",
-			Error:   "Token contains invalid operator ID",
-		})
-	}
-
-	// Cari operator di database
-	var operator models.Operator
-	if err := db.DB.Where("id = ?", operatorID).First(&operator).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
-			Message: "operator not found",
-			Error:   "No operator with the given ID",
-		})
-	}
-
 	// Ambil semua invoice dari database, preload data terkait InvoiceItems dan Products
 	var invoices []models.Invoice
-	if err := db.DB.Preload("InvoiceItems.Product").Preload("User").Find(&invoices).Error; err != nil {
+	if err := db.DB.Preload("InvoiceItems.Product.Category").Preload("User", withDeleted).Find(&invoices).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 			Message: "failed to retrieve invoices",
 			Error:   "Failed to retrieve invoices from the database",
@@ -488,53 +470,20 @@ func GetAllInvoicesForOperator(c *fiber.Ctx) error {
 	}
 
 	// Return all invoices as JSON response
-	return c.JSON(invoices)
+	return c.JSON(dto.FromInvoices(invoices, auth.RoleOperator))
 }
 
 // ApproveMultipleInvoices memungkinkan operator untuk menyetujui beberapa pesanan sekaligus
 func ApproveInvoices(c *fiber.Ctx) error {
-	// Ambil token dari cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Parse token dengan claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid or expired token",
-		})
-	}
-
-	// Ambil operatorID dari claims
-	claims, ok := token.Claims.(*jwt.StandardClaims)
+	// Principal operator di-set oleh middleware auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Message: "unauthenticated",
-			Error:   "Invalid token claims",
-		})
-	}
-
-	operatorID := claims.Subject
-	var operator models.Operator
-	if err := db.DB.Where("id = ?", operatorID).First(&operator).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
-			Message: "operator not found",
-			Error:   "No operator with the given ID",
+			Error:   "Invalid or expired token",
 		})
 	}
+	operatorID := principal.Subject()
 
 	// Parse daftar order IDs yang ingin di-approve
 	var orderIDs []int
@@ -545,65 +494,27 @@ func ApproveInvoices(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update status setiap order
+	// Setiap order diproses dalam transaksinya sendiri: reservasi stok dicatat sebagai ProductOut
+	failed := fiber.Map{}
 	for _, orderID := range orderIDs {
-		if err := db.DB.Model(&models.Invoice{}).Where("id = ?", orderID).Update("status", "Approved").Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-				Message: "failed to update status",
-				Error:   "Failed to update status for order ID " + strconv.Itoa(orderID),
-			})
+		if err := commitInvoiceStock(db.DB, orderID, operatorID); err != nil {
+			failed[strconv.Itoa(orderID)] = invoiceStockErrorMessage(err)
 		}
 	}
 
+	if len(failed) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Some orders could not be approved",
+			"failed":  failed,
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Orders successfully approved",
 	})
 }
 
 func RejectInvoices(c *fiber.Ctx) error {
-	// Ambil token dari cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Parse token dengan claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid or expired token",
-		})
-	}
-
-	// Ambil operatorID dari claims
-	claims, ok := token.Claims.(*jwt.StandardClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid token claims",
-		})
-	}
-
-	operatorID := claims.Subject
-	var operator models.Operator
-	if err := db.DB.Where("id = ?", operatorID).First(&operator).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
-			Message: "operator not found",
-			Error:   "No operator with the given ID",
-		})
-	}
-
 	// Parse daftar order IDs yang ingin di-reject
 	var orderIDs []int
 	if err := c.BodyParser(&orderIDs); err != nil {
@@ -613,70 +524,32 @@ func RejectInvoices(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update status setiap order menjadi "Rejected"
+	// Setiap order diproses dalam transaksinya sendiri: stok yang direservasi dikembalikan
+	failed := fiber.Map{}
 	for _, orderID := range orderIDs {
-		if err := db.DB.Model(&models.Invoice{}).Where("id = ?", orderID).Update("status", "Rejected").Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-				Message: "failed to update status",
-				Error:   "Failed to update status for order ID " + strconv.Itoa(orderID),
-			})
+		if err := releaseInvoiceStock(db.DB, orderID, models.InvoiceStatusRejected, ""); err != nil {
+			failed[strconv.Itoa(orderID)] = invoiceStockErrorMessage(err)
 		}
 	}
 
+	if len(failed) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Some orders could not be rejected",
+			"failed":  failed,
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Orders successfully rejected",
 	})
 }
 
 func GetAcceptInvoice(c *fiber.Ctx) error {
-	// Ambil token dari cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Parse token dengan claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid or expired token",
-		})
-	}
-
-	// Ambil operatorID dari claims
-	claims, ok := token.Claims.(*jwt.StandardClaims)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid token claims",
-		})
-	}
-
-	operatorID := claims.Subject
-	var operator models.Operator
-	if err := db.DB.Where("id = ?", operatorID).First(&operator).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
-			Message: "operator not found",
-			Error:   "No operator with the given ID",
-		})
-	}
-
 	var invoices []models.Invoice
 	if err := db.DB.
-		Preload("InvoiceItems.Product"). // Preload relasi dengan InvoiceItems dan Product
-		Preload("User").                 // Preload relasi dengan User
-		Where("status = ?", "Approved"). // Filter berdasarkan status "Approved"
+		Preload("InvoiceItems.Product.Category"). // Preload relasi dengan InvoiceItems dan Product
+		Preload("User", withDeleted).    // Preload relasi dengan User, termasuk akun yang sudah dihapus
+		Where("status = ?", models.InvoiceStatusApproved). // Filter berdasarkan status "Approved"
 		Find(&invoices).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
 			Message: "failed to retrieve invoices",
@@ -686,51 +559,17 @@ func GetAcceptInvoice(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"message":  "Successfully retrieved accepted invoices",
-		"invoices": invoices,
+		"invoices": dto.FromInvoices(invoices, auth.RoleOperator),
 	})
 }
 
 func UpdateStatusInvoice(c *fiber.Ctx) error {
-	// Ambil token dari cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Parse token dengan claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
-			Message: "unauthenticated",
-			Error:   "Invalid or expired token",
-		})
-	}
-
-	// Ambil operatorID dari claims
-	claims, ok := token.Claims.(*jwt.StandardClaims)
+	// Principal operator di-set oleh middleware auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
 		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
 			Message: "unauthenticated",
-			Error:   "Invalid token claims",
-		})
-	}
-
-	operatorID := claims.Subject
-	var operator models.Operator
-	if err := db.DB.Where("id = ?", operatorID).First(&operator).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{
-			Message: "operator not found",
-			Error:   "No operator with the given ID",
+			Error:   "Invalid or expired token",
 		})
 	}
 
@@ -748,7 +587,13 @@ func UpdateStatusInvoice(c *fiber.Ctx) error {
 	}
 
 	// Validasi status_shipment
-	validStatus := []string{"Pending", "Shipped", "Delivered", "Returned", "Cancelled"}
+	validStatus := []string{
+		models.ShipmentStatusPending,
+		models.ShipmentStatusShipped,
+		models.ShipmentStatusDelivered,
+		models.ShipmentStatusReturned,
+		models.ShipmentStatusCancelled,
+	}
 	isValidStatus := false
 	for _, status := range validStatus {
 		if status == requestData.StatusShipment {
@@ -763,18 +608,22 @@ func UpdateStatusInvoice(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update status setiap order dengan status_shipment yang dipilih
+	// Setiap order diproses dalam transaksinya sendiri: Cancelled dan Returned mengembalikan stok
+	failed := fiber.Map{}
 	for _, orderID := range requestData.OrderIDs {
-		if err := db.DB.Model(&models.Invoice{}).Where("id = ?", orderID).Update("status_shipment", requestData.StatusShipment).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
-				Message: "failed to update status_shipment",
-				Error:   "Failed to update status_shipment for order ID " + strconv.Itoa(orderID),
-			})
+		if err := updateShipmentStatus(db.DB, orderID, requestData.StatusShipment, principal.Subject()); err != nil {
+			failed[strconv.Itoa(orderID)] = invoiceStockErrorMessage(err)
 		}
 	}
 
+	if len(failed) > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"message": "Some orders could not be updated",
+			"failed":  failed,
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Orders successfully updated with new shipment status",
 	})
 }
-
@@ -0,0 +1,395 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/search"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+var (
+	errUnknownCategory = errors.New("unknown category")
+	errCategoryCycle   = errors.New("a category cannot be placed under itself or its subcategories")
+	errCategorySlug    = errors.New("slug is already used by another category")
+	errCategoryNoSlug  = errors.New("slug must contain letters or digits")
+	errCategoryInUse   = errors.New("category still has products or subcategories; choose where to move them with move_to")
+)
+
+// findCategory mencari kategori berdasarkan ID atau slug (nama juga diterima
+// karena slug dibuat dari nama)
+func findCategory(tx *gorm.DB, value string) (*models.Category, error) {
+	var category models.Category
+	query := tx.Where("slug = ?", models.Slugify(value))
+	if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+		query = tx.Where("id = ?", id)
+	}
+
+	err := query.First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errUnknownCategory
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// productCategory mengambil kategori dari input produk: category_id, atau
+// category berupa nama/slug dari klien lama
+func productCategory(tx *gorm.DB, id uint, name string) (*models.Category, error) {
+	if id != 0 {
+		return findCategory(tx, strconv.FormatUint(uint64(id), 10))
+	}
+	return findCategory(tx, name)
+}
+
+// categoryFilter mengembalikan ID kategori yang dibuka beserta turunannya.
+// Customer hanya melihat kategori aktif.
+func categoryFilter(tx *gorm.DB, value string, role string) ([]uint, error) {
+	category, err := findCategory(tx, value)
+	if err != nil {
+		return nil, err
+	}
+	activeOnly := role == auth.RoleUser
+	if activeOnly && !category.Active {
+		return nil, errUnknownCategory
+	}
+	return models.CategoryDescendants(tx, category.ID, activeOnly)
+}
+
+// checkCategoryParent memastikan induk ada dan tidak membuat siklus. id adalah
+// kategori yang diedit, 0 untuk kategori baru.
+func checkCategoryParent(tx *gorm.DB, id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&models.Category{}).Where("id = ?", *parentID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errUnknownCategory
+	}
+	if id == 0 {
+		return nil
+	}
+
+	descendants, err := models.CategoryDescendants(tx, id, false)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if descendant == *parentID {
+			return errCategoryCycle
+		}
+	}
+	return nil
+}
+
+// categorySlug membuat slug dari input dan menolak slug yang sudah dipakai
+func categorySlug(tx *gorm.DB, id uint, data *validators.CategoryInput) (string, error) {
+	slug := data.Slug
+	if slug == "" {
+		slug = data.Name
+	}
+	slug = models.Slugify(slug)
+	if slug == "" {
+		return "", errCategoryNoSlug
+	}
+
+	var count int64
+	if err := tx.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, id).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "", errCategorySlug
+	}
+	return slug, nil
+}
+
+// categoryErrorResponse menerjemahkan error kategori menjadi response
+func categoryErrorResponse(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case errors.Is(err, errUnknownCategory):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", "Parent or target category not found"})
+	case errors.Is(err, errCategorySlug), errors.Is(err, errCategoryInUse):
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{message, err.Error()})
+	case errors.Is(err, errCategoryCycle), errors.Is(err, errCategoryNoSlug):
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{message, err.Error()})
+}
+
+// refreshSearchIndex membangun ulang index pencarian setelah nama kategori atau
+// kategori produk berubah
+func refreshSearchIndex() {
+	if _, err := search.Rebuild(db.DB); err != nil {
+		log.Printf("Cannot rebuild search index: %v\n", err)
+	}
+}
+
+// ListCategories godoc
+// @Summary List categories
+// @Description List active product categories as a tree ordered by sort order. Browse one with the category parameter of the product list; subcategories are included.
+// @Tags category
+// @Produce json
+// @Success 200 {array} dto.CategoryResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/categories [get]
+func ListCategories(c *fiber.Ctx) error {
+	var categories []models.Category
+	if err := db.DB.Where("active = ?", true).Find(&categories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve categories", err.Error()})
+	}
+	return c.JSON(dto.CategoryTree(categories))
+}
+
+// ListAllCategories godoc
+// @Summary List all categories
+// @Description Admin-only: list every category including inactive ones as a tree
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.CategoryResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/categories [get]
+func ListAllCategories(c *fiber.Ctx) error {
+	var categories []models.Category
+	if err := db.DB.Find(&categories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve categories", err.Error()})
+	}
+	return c.JSON(dto.CategoryTree(categories))
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Admin-only: create a product category, optionally under a parent category
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param category body validators.CategoryInput true "Category details"
+// @Success 201 {object} dto.CategoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/categories [post]
+func CreateCategory(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.CategoryInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var category models.Category
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, 0, data.ParentID); err != nil {
+			return err
+		}
+		slug, err := categorySlug(tx, 0, &data)
+		if err != nil {
+			return err
+		}
+
+		category = models.Category{
+			ParentID:  data.ParentID,
+			Name:      data.Name,
+			Slug:      slug,
+			SortOrder: data.SortOrder,
+			Active:    data.Active == nil || *data.Active,
+		}
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		// GORM melewati nilai false untuk kolom ber-default, jadi disimpan terpisah
+		if !category.Active {
+			if err := tx.Model(&category).Update("active", false).Error; err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, principal, "category.create", "category", category.ID, data)
+	})
+	if err != nil {
+		return categoryErrorResponse(c, err, "Cannot create category")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.FromCategory(&category))
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Admin-only: rename, move, reorder or (de)activate a category. A category cannot be moved under one of its own subcategories.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param category body validators.CategoryInput true "Category details"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/categories/{id} [put]
+func UpdateCategory(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid category ID"})
+	}
+
+	var data validators.CategoryInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"category not found", "No category with the given ID"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryParent(tx, category.ID, data.ParentID); err != nil {
+			return err
+		}
+		slug, err := categorySlug(tx, category.ID, &data)
+		if err != nil {
+			return err
+		}
+
+		changes := map[string]interface{}{
+			"parent_id":  data.ParentID,
+			"name":       data.Name,
+			"slug":       slug,
+			"sort_order": data.SortOrder,
+		}
+		if data.Active != nil {
+			changes["active"] = *data.Active
+		}
+		if err := tx.Model(&category).Updates(changes).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "category.update", "category", category.ID, data)
+	})
+	if err != nil {
+		return categoryErrorResponse(c, err, "Cannot update category")
+	}
+
+	go refreshSearchIndex()
+
+	db.DB.First(&category, id)
+	return c.JSON(dto.FromCategory(&category))
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Admin-only: delete a category. Its products and subcategories move to move_to, or to its parent when move_to is omitted. Use move_to to merge duplicate categories (e.g. "Sepatu" into "Shoes").
+// @Tags admin
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param move_to query int false "Category that receives the products and subcategories"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/categories/{id} [delete]
+func DeleteCategory(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid category ID"})
+	}
+
+	var category models.Category
+	if err := db.DB.First(&category, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"category not found", "No category with the given ID"})
+	}
+
+	target := category.ParentID
+	if moveTo := c.Query("move_to"); moveTo != "" {
+		targetID, err := strconv.ParseUint(moveTo, 10, 64)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid move_to category ID"})
+		}
+		moved := uint(targetID)
+		target = &moved
+	}
+
+	var movedProducts, movedChildren int64
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Target tidak boleh kategori ini sendiri atau turunannya
+		if target != nil {
+			if err := checkCategoryParent(tx, category.ID, target); err != nil {
+				return err
+			}
+		}
+
+		products := tx.Model(&models.Product{}).Where("category_id = ?", category.ID)
+		children := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID)
+		if target == nil {
+			if err := products.Count(&movedProducts).Error; err != nil {
+				return err
+			}
+			if err := children.Count(&movedChildren).Error; err != nil {
+				return err
+			}
+			if movedProducts > 0 || movedChildren > 0 {
+				return errCategoryInUse
+			}
+		} else {
+			result := products.Update("category_id", *target)
+			if result.Error != nil {
+				return result.Error
+			}
+			movedProducts = result.RowsAffected
+			result = children.Update("parent_id", *target)
+			if result.Error != nil {
+				return result.Error
+			}
+			movedChildren = result.RowsAffected
+		}
+
+		if err := tx.Delete(&category).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "category.delete", "category", category.ID, fiber.Map{
+			"name":           category.Name,
+			"move_to":        target,
+			"moved_products": movedProducts,
+			"moved_children": movedChildren,
+		})
+	})
+	if err != nil {
+		return categoryErrorResponse(c, err, "Cannot delete category")
+	}
+
+	if movedProducts > 0 {
+		go refreshSearchIndex()
+	}
+
+	return c.JSON(SuccessResponse{Message: "Category deleted"})
+}
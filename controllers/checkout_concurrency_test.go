@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// TestCreateInvoiceConcurrentCheckout menjalankan lebih banyak checkout paralel
+// daripada stok yang tersedia untuk satu produk. Tepat stock checkout harus
+// berhasil, sisanya mendapat 409 yang menyebut produknya, dan stok tidak pernah
+// negatif.
+func TestCreateInvoiceConcurrentCheckout(t *testing.T) {
+	conn := openTestDB(t)
+
+	const stock = 5
+	const buyers = 20
+
+	now := time.Now()
+	suffix := strconv.FormatInt(now.UnixNano(), 36)
+
+	product := models.Product{ProductName: "Concurrency Test " + suffix, BrandName: "Test", Price: 1000, Quantity: stock, Status: true}
+	if err := conn.Create(&product).Error; err != nil {
+		t.Fatalf("cannot seed product: %v", err)
+	}
+
+	userIDs := make([]int, buyers)
+	for i := range userIDs {
+		user := models.User{
+			Email:           fmt.Sprintf("buyer-%d-%s@example.test", i, suffix),
+			Username:        "buyer",
+			Password:        []byte("x"),
+			EmailVerifiedAt: &now,
+		}
+		if err := conn.Create(&user).Error; err != nil {
+			t.Fatalf("cannot seed user: %v", err)
+		}
+		userIDs[i] = user.ID
+
+		address := models.UserAddress{UserID: user.ID, Label: "Rumah", IsDefault: true, Address: models.Address{RecipientName: "Buyer", Street: "Jl. Test"}}
+		cart := models.CartItem{ProductID: product.ID, UserID: strconv.Itoa(user.ID), Quantity: 1}
+		if err := conn.Create(&address).Error; err != nil {
+			t.Fatalf("cannot seed address: %v", err)
+		}
+		if err := conn.Create(&cart).Error; err != nil {
+			t.Fatalf("cannot seed cart: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		var invoiceIDs []int
+		conn.Model(&models.Invoice{}).Where("user_id IN ?", userKeys(userIDs)).Pluck("id", &invoiceIDs)
+		conn.Where("invoice_id IN ?", append(invoiceIDs, 0)).Delete(&models.InvoiceItem{})
+		conn.Where("id IN ?", append(invoiceIDs, 0)).Delete(&models.Invoice{})
+		conn.Where("user_id IN ?", userKeys(userIDs)).Delete(&models.CartItem{})
+		conn.Where("user_id IN ?", userIDs).Delete(&models.UserAddress{})
+		conn.Unscoped().Where("id IN ?", userIDs).Delete(&models.User{})
+		conn.Delete(&product)
+	})
+
+	app := fiber.New()
+	app.Post("/api/createInvoice", withTestUser, RequireVerifiedEmail, CreateInvoice)
+
+	// Pantau stok selama checkout berjalan untuk menangkap nilai negatif sesaat
+	var lowest atomic.Int64
+	lowest.Store(stock)
+	done := make(chan struct{})
+	var watcher sync.WaitGroup
+	watcher.Add(1)
+	go func() {
+		defer watcher.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			var quantity int64
+			if err := conn.Model(&models.Product{}).Where("id = ?", product.ID).Select("quantity").Scan(&quantity).Error; err == nil && quantity < lowest.Load() {
+				lowest.Store(quantity)
+			}
+		}
+	}()
+
+	var created, conflicts atomic.Int32
+	errs := make(chan string, buyers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, userID := range userIDs {
+		wg.Add(1)
+		go func(userID int) {
+			defer wg.Done()
+			<-start
+
+			req := httptest.NewRequest(http.MethodPost, "/api/createInvoice", nil)
+			req.Header.Set(testUserHeader, strconv.Itoa(userID))
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				errs <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+
+			switch resp.StatusCode {
+			case fiber.StatusCreated:
+				created.Add(1)
+			case fiber.StatusConflict:
+				conflicts.Add(1)
+				var body InsufficientStockResponse
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					errs <- "cannot decode 409 body: " + err.Error()
+					return
+				}
+				if len(body.Items) != 1 || body.Items[0].ProductID != product.ID || body.Items[0].ProductName != product.ProductName {
+					errs <- fmt.Sprintf("409 body does not name the product: %+v", body)
+				}
+			default:
+				errs <- fmt.Sprintf("unexpected status %d", resp.StatusCode)
+			}
+		}(userID)
+	}
+	close(start)
+	wg.Wait()
+	close(done)
+	watcher.Wait()
+	close(errs)
+
+	for msg := range errs {
+		t.Error(msg)
+	}
+	if created.Load() != stock {
+		t.Errorf("expected %d successful checkouts, got %d", stock, created.Load())
+	}
+	if conflicts.Load() != buyers-stock {
+		t.Errorf("expected %d insufficient stock responses, got %d", buyers-stock, conflicts.Load())
+	}
+
+	var final models.Product
+	if err := conn.First(&final, product.ID).Error; err != nil {
+		t.Fatalf("cannot reload product: %v", err)
+	}
+	if final.Quantity != 0 {
+		t.Errorf("expected final quantity 0, got %d", final.Quantity)
+	}
+	if final.Status {
+		t.Error("expected product status to be out of stock")
+	}
+	if lowest.Load() < 0 {
+		t.Errorf("quantity went negative during checkout: %d", lowest.Load())
+	}
+}
+
+// userKeys mengubah ID user menjadi bentuk string yang dipakai kolom user_id keranjang dan invoice
+func userKeys(ids []int) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = strconv.Itoa(id)
+	}
+	return keys
+}
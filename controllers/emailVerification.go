@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/mailer"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// emailVerificationTTL adalah masa berlaku link verifikasi email
+	emailVerificationTTL = 24 * time.Hour
+	// emailVerificationResendInterval membatasi seberapa sering link dikirim ulang
+	emailVerificationResendInterval = time.Minute
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// appURL membentuk URL absolut dari APP_BASE_URL untuk link di email
+func appURL(path string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// frontendURL membentuk URL halaman frontend dari FRONTEND_URL, misalnya form reset password
+func frontendURL(path string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return strings.TrimRight(base, "/") + path
+}
+
+// sendVerificationEmail membuat token verifikasi baru untuk email user saat ini,
+// membatalkan token lama yang belum dipakai, lalu mengirim link-nya lewat mailer.
+func sendVerificationEmail(user *models.User) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).
+			Delete(&models.EmailVerificationToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.EmailVerificationToken{
+			UserID:    user.ID,
+			Email:     user.Email,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(emailVerificationTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := appURL("/api/verify-email?token=" + url.QueryEscape(token))
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"Please confirm your email address by opening the link below:\n\n" +
+			link + "\n\n" +
+			"The link expires in 24 hours. If you did not create an account, you can ignore this email.",
+	})
+}
+
+// consumeVerificationToken menandai token terpakai dan memverifikasi email user
+// dalam satu transaksi. Token untuk email yang sudah diganti dianggap tidak valid.
+func consumeVerificationToken(token string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.EmailVerificationToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(token), time.Now()).
+			First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidVerificationToken
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update bersyarat agar token yang dipakai bersamaan hanya berhasil sekali
+		now := time.Now()
+		result := tx.Model(&models.EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidVerificationToken
+		}
+
+		result = tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", record.UserID, record.Email).
+			Update("email_verified_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidVerificationToken
+		}
+		return nil
+	})
+}
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Confirm the user's email address with the token sent by email
+// @Tags auth
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/verify-email [get]
+func VerifyEmail(c *fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Missing verification token"})
+	}
+
+	if err := consumeVerificationToken(token); err != nil {
+		if errors.Is(err, errInvalidVerificationToken) {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Email verification failed", err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Email verification failed", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Email verified"})
+}
+
+// ResendVerificationEmail godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to the authenticated user's email address
+// @Tags auth
+// @Produce json
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/verify-email/resend [post]
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, principal.SubjectID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+
+	if user.EmailVerified() {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Email is already verified"})
+	}
+
+	var recent int64
+	db.DB.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-emailVerificationResendInterval)).
+		Count(&recent)
+	if recent > 0 {
+		return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{"too many requests", "Please wait a minute before requesting another email"})
+	}
+
+	if err := sendVerificationEmail(&user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot send verification email", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Verification email sent"})
+}
+
+// RequireVerifiedEmail menolak request dari user yang belum memverifikasi emailnya
+func RequireVerifiedEmail(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var user models.User
+	if err := db.DB.Select("id", "email_verified_at").First(&user, principal.SubjectID).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+	}
+
+	if !user.EmailVerified() {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"Email not verified", "Please verify your email address before checking out"})
+	}
+
+	return c.Next()
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errInvoiceNotFound    = errors.New("invoice not found")
+	errInvoiceNotPending  = errors.New("invoice is not pending")
+	errInvoiceNotApproved = errors.New("invoice is not approved")
+	errShipmentClosed     = errors.New("shipment is already cancelled or returned")
+	errCartEmpty          = errors.New("cart is empty")
+)
+
+// ShortStockItem menjelaskan satu produk yang stoknya tidak mencukupi saat checkout
+type ShortStockItem struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Requested   int    `json:"requested"`
+	Available   int    `json:"available"`
+}
+
+// InsufficientStockResponse dikembalikan dengan status 409 saat checkout gagal karena stok
+type InsufficientStockResponse struct {
+	Message string           `json:"message"`
+	Error   string           `json:"error"`
+	Items   []ShortStockItem `json:"items"`
+}
+
+// insufficientStockError dikembalikan saat stok satu atau lebih produk tidak cukup untuk reservasi
+type insufficientStockError struct {
+	Items []ShortStockItem
+}
+
+func (e *insufficientStockError) Error() string {
+	names := make([]string, 0, len(e.Items))
+	for _, item := range e.Items {
+		names = append(names, fmt.Sprintf("%s (requested %d, available %d)", item.ProductName, item.Requested, item.Available))
+	}
+	return "insufficient stock for " + strings.Join(names, ", ")
+}
+
+// lockProduct mengambil produk dengan row lock di dalam transaksi tx
+func lockProduct(tx *gorm.DB, productID int) (*models.Product, error) {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, productID).Error; err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// shortStock membuat insufficientStockError untuk satu produk
+func shortStock(product *models.Product, requested int) error {
+	return &insufficientStockError{Items: []ShortStockItem{{
+		ProductID:   product.ID,
+		ProductName: product.ProductName,
+		Requested:   requested,
+		Available:   product.Quantity,
+	}}}
+}
+
+// insufficientStockResponse membentuk body response 409 yang menyebutkan item yang kurang
+func insufficientStockResponse(err *insufficientStockError) InsufficientStockResponse {
+	return InsufficientStockResponse{
+		Message: "insufficient stock",
+		Error:   err.Error(),
+		Items:   err.Items,
+	}
+}
+
+// syncProductStatus menyamakan Product.Status dengan stok terkini
+func syncProductStatus(tx *gorm.DB, productIDs []int) error {
+	if len(productIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.Product{}).
+		Where("id IN ?", productIDs).
+		Update("status", gorm.Expr("quantity > 0")).Error
+}
+
+// reserveStock mengurangi stok untuk setiap item keranjang yang akan dijadikan invoice.
+// Pengurangan memakai update bersyarat (quantity >= ?) sehingga checkout paralel
+// tidak pernah membuat stok negatif. Semua item yang kurang dikumpulkan dalam
+// satu insufficientStockError.
+func reserveStock(tx *gorm.DB, cartItems []models.CartItem) error {
+	var short []ShortStockItem
+	productIDs := make([]int, 0, len(cartItems))
+
+	for _, item := range cartItems {
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND quantity >= ?", item.ProductID, item.Quantity).
+			Update("quantity", gorm.Expr("quantity - ?", item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			var product models.Product
+			if err := tx.Select("id", "product_name", "quantity").First(&product, item.ProductID).Error; err != nil {
+				return err
+			}
+			short = append(short, ShortStockItem{
+				ProductID:   product.ID,
+				ProductName: product.ProductName,
+				Requested:   item.Quantity,
+				Available:   product.Quantity,
+			})
+			continue
+		}
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	if len(short) > 0 {
+		return &insufficientStockError{Items: short}
+	}
+
+	return syncProductStatus(tx, productIDs)
+}
+
+// reserveInvoiceItems mengurangi stok untuk item invoice lama yang dibuat sebelum
+// reservasi stok ada, dengan aturan yang sama seperti checkout
+func reserveInvoiceItems(tx *gorm.DB, items []models.InvoiceItem) error {
+	cartItems := make([]models.CartItem, len(items))
+	for i, item := range items {
+		cartItems[i] = models.CartItem{ProductID: item.ProductID, Quantity: item.Quantity}
+	}
+	return reserveStock(tx, cartItems)
+}
+
+// restockInvoiceItems mengembalikan stok untuk item invoice yang reservasinya dibatalkan
+func restockInvoiceItems(tx *gorm.DB, items []models.InvoiceItem) error {
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		if err := tx.Model(&models.Product{}).
+			Where("id = ?", item.ProductID).
+			Update("quantity", gorm.Expr("quantity + ?", item.Quantity)).Error; err != nil {
+			return err
+		}
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	return syncProductStatus(tx, productIDs)
+}
+
+// lockInvoice mengambil invoice beserta item-nya dengan row lock. Jika userID
+// tidak kosong, invoice juga harus milik user tersebut.
+func lockInvoice(tx *gorm.DB, invoiceID int, userID string) (*models.Invoice, error) {
+	// Item diurutkan berdasarkan product_id agar urutan row lock produk konsisten
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("InvoiceItems", func(db *gorm.DB) *gorm.DB { return db.Order("product_id") })
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var invoice models.Invoice
+	if err := query.First(&invoice, invoiceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvoiceNotFound
+		}
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// lockPendingInvoice seperti lockInvoice, tetapi memastikan statusnya masih Pending
+func lockPendingInvoice(tx *gorm.DB, invoiceID int, userID string) (*models.Invoice, error) {
+	invoice, err := lockInvoice(tx, invoiceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice.Status != models.InvoiceStatusPending {
+		return nil, errInvoiceNotPending
+	}
+	return invoice, nil
+}
+
+// releaseInvoiceStock mengembalikan stok yang direservasi oleh invoice Pending
+// dan mengubah statusnya (Rejected atau Cancelled) dalam satu transaksi. Invoice
+// lama yang tidak memegang stok hanya diubah statusnya.
+func releaseInvoiceStock(db *gorm.DB, invoiceID int, status string, userID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := lockPendingInvoice(tx, invoiceID, userID)
+		if err != nil {
+			return err
+		}
+
+		if invoice.StockHeld {
+			if err := restockInvoiceItems(tx, invoice.InvoiceItems); err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(invoice).Updates(map[string]interface{}{
+			"status":     status,
+			"stock_held": false,
+		}).Error
+	})
+}
+
+// commitInvoiceStock mengubah reservasi invoice Pending menjadi catatan ProductOut
+// dan menandai invoice sebagai Approved dalam satu transaksi.
+func commitInvoiceStock(db *gorm.DB, invoiceID int, operatorID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := lockPendingInvoice(tx, invoiceID, "")
+		if err != nil {
+			return err
+		}
+
+		// Invoice lama belum mereservasi stok, jadi stoknya diambil sekarang
+		if !invoice.StockHeld {
+			if err := reserveInvoiceItems(tx, invoice.InvoiceItems); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range invoice.InvoiceItems {
+			productOut := models.ProductOut{
+				ProductID:  uint(item.ProductID),
+				Quantity:   item.Quantity,
+				OperatorID: operatorID,
+				InvoiceID:  &invoice.ID,
+				CreatedAt:  time.Now(),
+			}
+			if err := tx.Create(&productOut).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(invoice).Updates(map[string]interface{}{
+			"status":     models.InvoiceStatusApproved,
+			"stock_held": true,
+		}).Error
+	})
+}
+
+// shipmentClosed menandakan status pengiriman final yang stoknya sudah dikembalikan
+func shipmentClosed(status string) bool {
+	return status == models.ShipmentStatusCancelled || status == models.ShipmentStatusReturned
+}
+
+// updateShipmentStatus mengubah status pengiriman invoice Approved. Saat pengiriman
+// dibatalkan atau diretur, stok yang keluar dikembalikan dan dicatat sebagai
+// ProductIn dalam transaksi yang sama; setelah itu statusnya tidak bisa diubah lagi.
+func updateShipmentStatus(db *gorm.DB, invoiceID int, status string, operatorID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		invoice, err := lockInvoice(tx, invoiceID, "")
+		if err != nil {
+			return err
+		}
+		if invoice.Status != models.InvoiceStatusApproved {
+			return errInvoiceNotApproved
+		}
+		if shipmentClosed(invoice.StatusShipment) {
+			return errShipmentClosed
+		}
+
+		changes := map[string]interface{}{"status_shipment": status}
+		if shipmentClosed(status) && invoice.StockHeld {
+			if err := restockInvoiceItems(tx, invoice.InvoiceItems); err != nil {
+				return err
+			}
+			for _, item := range invoice.InvoiceItems {
+				if err := tx.Create(&models.ProductIn{
+					ProductID:  item.ProductID,
+					Quantity:   item.Quantity,
+					OperatorID: operatorID,
+					InvoiceID:  &invoice.ID,
+					CreatedAt:  time.Now(),
+				}).Error; err != nil {
+					return err
+				}
+			}
+			changes["stock_held"] = false
+		}
+
+		return tx.Model(invoice).Updates(changes).Error
+	})
+}
+
+// recordStockAdjustment mencatat perubahan stok manual sebagai ProductIn (delta
+// positif) atau ProductOut (delta negatif) agar laporan stok tetap konsisten
+func recordStockAdjustment(tx *gorm.DB, productID int, delta int, operatorID string) error {
+	switch {
+	case delta > 0:
+		return tx.Create(&models.ProductIn{
+			ProductID:  productID,
+			Quantity:   delta,
+			OperatorID: operatorID,
+			CreatedAt:  time.Now(),
+		}).Error
+	case delta < 0:
+		return tx.Create(&models.ProductOut{
+			ProductID:  uint(productID),
+			Quantity:   -delta,
+			OperatorID: operatorID,
+			CreatedAt:  time.Now(),
+		}).Error
+	}
+	return nil
+}
+
+// invoiceStockErrorMessage menerjemahkan error siklus invoice menjadi pesan untuk response
+func invoiceStockErrorMessage(err error) string {
+	var stockErr *insufficientStockError
+	switch {
+	case errors.Is(err, errInvoiceNotFound):
+		return "Invoice not found"
+	case errors.Is(err, errInvoiceNotPending):
+		return "Invoice is no longer pending"
+	case errors.Is(err, errInvoiceNotApproved):
+		return "Only approved invoices can be shipped"
+	case errors.Is(err, errShipmentClosed):
+		return "Shipment is already cancelled or returned"
+	case errors.As(err, &stockErr):
+		return stockErr.Error()
+	default:
+		return "Failed to update invoice"
+	}
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// TestInvoiceStockOnlyMovesHeldStock memastikan invoice Pending lama (tanpa
+// reservasi) tidak menambah stok saat ditolak, stoknya diambil saat disetujui,
+// dan pengiriman yang diretur mengembalikan stok tepat satu kali.
+func TestInvoiceStockOnlyMovesHeldStock(t *testing.T) {
+	conn := openTestDB(t)
+
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	product := models.Product{ProductName: "Invoice Stock Test " + suffix, BrandName: "Test", Price: 1000, Quantity: 10, Status: true}
+	if err := conn.Create(&product).Error; err != nil {
+		t.Fatalf("cannot seed product: %v", err)
+	}
+
+	legacyInvoice := func() models.Invoice {
+		t.Helper()
+		invoice := models.Invoice{UserID: "0", TotalPrice: 2000, CreatedAt: time.Now(), Status: models.InvoiceStatusPending}
+		if err := conn.Create(&invoice).Error; err != nil {
+			t.Fatalf("cannot seed invoice: %v", err)
+		}
+		item := models.InvoiceItem{InvoiceID: invoice.ID, ProductID: product.ID, Quantity: 2, Price: 1000, Total: 2000}
+		if err := conn.Create(&item).Error; err != nil {
+			t.Fatalf("cannot seed invoice item: %v", err)
+		}
+		return invoice
+	}
+	rejected, approved := legacyInvoice(), legacyInvoice()
+
+	t.Cleanup(func() {
+		ids := []int{rejected.ID, approved.ID}
+		conn.Where("invoice_id IN ?", ids).Delete(&models.ProductOut{})
+		conn.Where("invoice_id IN ?", ids).Delete(&models.ProductIn{})
+		conn.Where("invoice_id IN ?", ids).Delete(&models.InvoiceItem{})
+		conn.Where("id IN ?", ids).Delete(&models.Invoice{})
+		conn.Delete(&product)
+	})
+
+	quantity := func() int {
+		t.Helper()
+		var current models.Product
+		if err := conn.First(&current, product.ID).Error; err != nil {
+			t.Fatalf("cannot reload product: %v", err)
+		}
+		return current.Quantity
+	}
+
+	if err := releaseInvoiceStock(conn, rejected.ID, models.InvoiceStatusRejected, ""); err != nil {
+		t.Fatalf("reject legacy invoice: %v", err)
+	}
+	if got := quantity(); got != 10 {
+		t.Errorf("rejecting a legacy invoice changed stock: got %d, want 10", got)
+	}
+
+	if err := commitInvoiceStock(conn, approved.ID, "op"); err != nil {
+		t.Fatalf("approve legacy invoice: %v", err)
+	}
+	if got := quantity(); got != 8 {
+		t.Errorf("approving a legacy invoice should take its stock: got %d, want 8", got)
+	}
+
+	if err := updateShipmentStatus(conn, approved.ID, models.ShipmentStatusShipped, "op"); err != nil {
+		t.Fatalf("ship invoice: %v", err)
+	}
+	if err := updateShipmentStatus(conn, approved.ID, models.ShipmentStatusReturned, "op"); err != nil {
+		t.Fatalf("return invoice: %v", err)
+	}
+	if got := quantity(); got != 10 {
+		t.Errorf("returned shipment should restock: got %d, want 10", got)
+	}
+
+	err := updateShipmentStatus(conn, approved.ID, models.ShipmentStatusCancelled, "op")
+	if !errors.Is(err, errShipmentClosed) {
+		t.Errorf("expected errShipmentClosed after return, got %v", err)
+	}
+	if got := quantity(); got != 10 {
+		t.Errorf("closed shipment must not restock again: got %d, want 10", got)
+	}
+
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		return updateShipmentStatus(tx, rejected.ID, models.ShipmentStatusShipped, "op")
+	})
+	if !errors.Is(err, errInvoiceNotApproved) {
+		t.Errorf("expected errInvoiceNotApproved for a rejected invoice, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/validators"
+)
+
+// loginThrottleResponse mengirim 429 dengan header Retry-After. Isinya sama untuk
+// akun yang ada maupun tidak sehingga lockout tidak membocorkan keberadaan akun.
+func loginThrottleResponse(c *fiber.Ctx, err error) error {
+	var throttled *auth.ThrottledError
+	if !errors.As(err, &throttled) {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not login", err.Error()})
+	}
+
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(ErrorResponse{
+		Message: "Too many login attempts",
+		Error:   "Please try again in " + strconv.Itoa(seconds) + " seconds",
+	})
+}
+
+// recordLoginFailure mencatat login gagal; error store hanya di-log agar
+// response ke pemanggil tetap seragam
+func recordLoginFailure(c *fiber.Ctx, role string, identifier string) {
+	if err := auth.LoginFailed(c, role, identifier); err != nil {
+		log.Printf("Failed to record failed %s login: %v\n", role, err)
+	}
+}
+
+// recordLoginSuccess mereset penghitung login gagal milik akun
+func recordLoginSuccess(role string, identifier string) {
+	if err := auth.LoginSucceeded(role, identifier); err != nil {
+		log.Printf("Failed to reset %s login attempts: %v\n", role, err)
+	}
+}
+
+// UnlockLogin godoc
+// @Summary Unlock a login
+// @Description Admin-only: clear the failed-login counter and lockout of a user (by email), operator (by operator_id) or admin (by admin_id), and optionally the lockout of the IP address they log in from
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param unlock body validators.UnlockLoginInput true "Account to unlock"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/logins/unlock [post]
+func UnlockLogin(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.UnlockLoginInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	if err := auth.UnlockAccount(data.Role, data.Identifier, data.IP); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot unlock login", err.Error()})
+	}
+
+	if err := recordAudit(db.DB, principal, "login.unlock", data.Role, 0, fiber.Map{"identifier": data.Identifier, "ip": data.IP}); err != nil {
+		log.Printf("Failed to record login unlock: %v\n", err)
+	}
+
+	return c.JSON(SuccessResponse{Message: "Login unlocked"})
+}
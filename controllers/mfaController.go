@@ -0,0 +1,624 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount adalah jumlah recovery code yang dibuat setiap kali
+const recoveryCodeCount = 10
+
+// MFAChallengeResponse dikirim oleh login staff jika MFA aktif. mfa_token
+// dipakai di langkah kedua bersama kode TOTP atau recovery code.
+type MFAChallengeResponse struct {
+	Message     string    `json:"message"`
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MFAEnrollmentResponse berisi secret TOTP yang harus dimasukkan ke aplikasi authenticator
+type MFAEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse menampilkan recovery code satu kali saja
+type RecoveryCodesResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFASettingResponse struct {
+	Required bool `json:"required"`
+}
+
+// staffMFAState berisi kolom MFA dari tabel operators atau admins
+type staffMFAState struct {
+	ID          uint
+	Code        string // operator_id atau admin_id
+	MFASecret   string // sudah didekripsi oleh loadMFAState
+	MFAEnabled  bool
+	MFALastStep int64
+}
+
+func loadMFAState(role string, id uint) (*staffMFAState, error) {
+	var state staffMFAState
+	err := db.DB.Model(staffModel(role)).
+		Select("id", staffCodeColumn(role)+" AS code", "mfa_secret", "mfa_enabled", "mfa_last_step").
+		Where("id = ?", id).
+		Take(&state).Error
+	if err != nil {
+		return &state, err
+	}
+	state.MFASecret, err = auth.OpenSecret(state.MFASecret)
+	return &state, err
+}
+
+// mfaIssuer adalah nama yang tampil di aplikasi authenticator
+func mfaIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "go-restapi"
+}
+
+// mfaEnrollmentRequired bernilai true jika admin mewajibkan MFA dan akun belum mengaktifkannya
+func mfaEnrollmentRequired(enabled bool) (bool, error) {
+	if enabled {
+		return false, nil
+	}
+	return models.SettingBool(db.DB, models.SettingMFARequired)
+}
+
+// mfaChallengeResponse mengirim token tantangan sebagai ganti cookie sesi
+func mfaChallengeResponse(c *fiber.Ctx, role string, subjectID uint, counter int64) error {
+	token, expiresAt, err := auth.IssueMFAChallenge(role, subjectID, counter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not generate token", err.Error()})
+	}
+
+	return c.JSON(MFAChallengeResponse{
+		Message:     "MFA code required",
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// checkTOTP memvalidasi kode TOTP dan mencatat langkahnya dengan update bersyarat
+// sehingga kode yang sama tidak bisa dipakai dua kali, bahkan secara paralel.
+func checkTOTP(role string, state *staffMFAState, code string) (bool, error) {
+	step, ok := auth.ValidateTOTP(state.MFASecret, code, time.Now(), state.MFALastStep)
+	if !ok {
+		return false, nil
+	}
+
+	result := db.DB.Model(staffModel(role)).
+		Where("id = ? AND mfa_last_step < ?", state.ID, step).
+		Update("mfa_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// normalizeRecoveryCode mengabaikan huruf besar/kecil, spasi dan tanda hubung
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// useRecoveryCode menandai recovery code terpakai jika cocok dan belum pernah dipakai
+func useRecoveryCode(role string, subjectID uint, code string) (bool, error) {
+	result := db.DB.Model(&models.MFARecoveryCode{}).
+		Where("role = ? AND subject_id = ? AND code_hash = ? AND used_at IS NULL",
+			role, subjectID, auth.HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// verifySecondFactor menerima kode TOTP atau recovery code
+func verifySecondFactor(role string, state *staffMFAState, code string) (bool, error) {
+	ok, err := checkTOTP(role, state, code)
+	if ok || err != nil {
+		return ok, err
+	}
+	return useRecoveryCode(role, state.ID, code)
+}
+
+// newRecoveryCodes mengganti semua recovery code akun dengan set baru
+func newRecoveryCodes(tx *gorm.DB, role string, subjectID uint) ([]string, error) {
+	if err := tx.Where("role = ? AND subject_id = ?", role, subjectID).
+		Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf))[:10]
+		code := raw[:5] + "-" + raw[5:]
+
+		if err := tx.Create(&models.MFARecoveryCode{
+			Role:      role,
+			SubjectID: subjectID,
+			CodeHash:  auth.HashToken(raw),
+		}).Error; err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// parseMFACode membaca dan memvalidasi body MFACodeInput
+func parseMFACode(c *fiber.Ctx) (string, error) {
+	var data validators.MFACodeInput
+	if err := c.BodyParser(&data); err != nil {
+		return "", err
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return "", err
+	}
+	return data.Code, nil
+}
+
+// EnrollMFA godoc
+// @Summary Start TOTP enrollment
+// @Description Generate a new TOTP secret for the logged-in staff member. MFA is only enabled after the first code is verified.
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} MFAEnrollmentResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/mfa/enroll [post]
+// @Router /admin/mfa/enroll [post]
+func EnrollMFA(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+		}
+
+		state, err := loadMFAState(role, principal.SubjectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+		}
+		if state.MFAEnabled {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"MFA already enabled", "Disable MFA before enrolling a new device"})
+		}
+
+		secret, err := auth.NewTOTPSecret()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot start enrollment", err.Error()})
+		}
+		sealed, err := auth.SealSecret(secret)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot start enrollment", err.Error()})
+		}
+
+		if err := db.DB.Model(staffModel(role)).Where("id = ?", state.ID).Updates(map[string]interface{}{
+			"mfa_secret":    sealed,
+			"mfa_last_step": 0,
+		}).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot start enrollment", err.Error()})
+		}
+
+		return c.JSON(MFAEnrollmentResponse{
+			Secret:          secret,
+			ProvisioningURI: auth.TOTPProvisioningURI(mfaIssuer(), state.Code, secret),
+		})
+	}
+}
+
+// ConfirmMFA godoc
+// @Summary Verify TOTP enrollment
+// @Description Confirm enrollment with the first code from the authenticator app. Returns one-time recovery codes that are shown only once.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param code body validators.MFACodeInput true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/mfa/verify [post]
+// @Router /admin/mfa/verify [post]
+func ConfirmMFA(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+		}
+
+		code, err := parseMFACode(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+		}
+
+		state, err := loadMFAState(role, principal.SubjectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+		}
+		if state.MFAEnabled {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"MFA already enabled", "MFA is already enabled for this account"})
+		}
+		if state.MFASecret == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Start enrollment first"})
+		}
+
+		valid, err := checkTOTP(role, state, code)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot verify code", err.Error()})
+		}
+		if !valid {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid MFA code", "The code is incorrect or has already been used"})
+		}
+
+		var codes []string
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(staffModel(role)).Where("id = ?", state.ID).Update("mfa_enabled", true).Error; err != nil {
+				return err
+			}
+			if codes, err = newRecoveryCodes(tx, role, state.ID); err != nil {
+				return err
+			}
+			return recordAudit(tx, principal, role+".mfa.enable", role, state.ID, fiber.Map{})
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot enable MFA", err.Error()})
+		}
+
+		return c.JSON(RecoveryCodesResponse{Message: "MFA enabled", RecoveryCodes: codes})
+	}
+}
+
+// DisableMFA godoc
+// @Summary Disable MFA
+// @Description Turn off MFA for the logged-in staff member. Requires a current TOTP code or a recovery code, and is refused while MFA is mandatory.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param code body validators.MFACodeInput true "TOTP code or recovery code"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/mfa [delete]
+// @Router /admin/mfa [delete]
+func DisableMFA(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+		}
+
+		code, err := parseMFACode(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+		}
+
+		state, err := loadMFAState(role, principal.SubjectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+		}
+		if !state.MFAEnabled {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "MFA is not enabled"})
+		}
+
+		required, err := models.SettingBool(db.DB, models.SettingMFARequired)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot disable MFA", err.Error()})
+		}
+		if required {
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"forbidden", "MFA is required for all staff"})
+		}
+
+		valid, err := verifySecondFactor(role, state, code)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot verify code", err.Error()})
+		}
+		if !valid {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid MFA code", "The code is incorrect or has already been used"})
+		}
+
+		if err := resetMFA(principal, role, state.ID, role+".mfa.disable"); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot disable MFA", err.Error()})
+		}
+
+		return c.JSON(SuccessResponse{Message: "MFA disabled"})
+	}
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate MFA recovery codes
+// @Description Replace all recovery codes of the logged-in staff member. Requires a current TOTP code.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param code body validators.MFACodeInput true "TOTP code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/mfa/recovery-codes [post]
+// @Router /admin/mfa/recovery-codes [post]
+func RegenerateRecoveryCodes(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+		}
+
+		code, err := parseMFACode(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+		}
+
+		state, err := loadMFAState(role, principal.SubjectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+		}
+		if !state.MFAEnabled {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "MFA is not enabled"})
+		}
+
+		valid, err := checkTOTP(role, state, code)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot verify code", err.Error()})
+		}
+		if !valid {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid MFA code", "The code is incorrect or has already been used"})
+		}
+
+		var codes []string
+		err = db.DB.Transaction(func(tx *gorm.DB) error {
+			codes, err = newRecoveryCodes(tx, role, state.ID)
+			return err
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot generate recovery codes", err.Error()})
+		}
+
+		return c.JSON(RecoveryCodesResponse{Message: "Recovery codes regenerated", RecoveryCodes: codes})
+	}
+}
+
+// CompleteMFALogin godoc
+// @Summary Complete a staff login with MFA
+// @Description Second login step: exchange the mfa_token from the login response and a TOTP or recovery code for a session. The mfa_token can only be used for one successful login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body validators.MFALoginInput true "MFA token and code"
+// @Param X-Auth-Mode header string false "Set to token to receive tokens in the JSON body without cookies"
+// @Success 200 {object} LoginOperatorResponse "LoginOperatorResponse or LoginAdminResponse"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/login/mfa [post]
+// @Router /admin/login/mfa [post]
+func CompleteMFALogin(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var data validators.MFALoginInput
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+		}
+		if err := validators.Validate.Struct(data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+		}
+
+		subjectID, counter, err := auth.ParseMFAChallenge(role, data.MFAToken)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired MFA token"})
+		}
+
+		state, err := loadMFAState(role, subjectID)
+		if err != nil || !state.MFAEnabled {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired MFA token"})
+		}
+
+		// Kode MFA yang salah dihitung ke penghitung login gagal akun yang sama
+		if err := auth.CheckLogin(c, role, state.Code); err != nil {
+			return loginThrottleResponse(c, err)
+		}
+
+		valid, err := verifySecondFactor(role, state, data.Code)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot verify code", err.Error()})
+		}
+		if !valid {
+			recordLoginFailure(c, role, state.Code)
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Invalid MFA code", "The code is incorrect or has already been used"})
+		}
+
+		// Token tantangan hanya berlaku sekali: counter akun dinaikkan dengan update
+		// bersyarat, sehingga token yang sama (bahkan paralel) tidak bisa dipakai lagi
+		result := db.DB.Model(staffModel(role)).
+			Where("id = ? AND mfa_challenge_counter = ?", subjectID, counter).
+			Update("mfa_challenge_counter", counter+1)
+		if result.Error != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot verify code", result.Error.Error()})
+		}
+		if result.RowsAffected == 0 {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired MFA token"})
+		}
+
+		if role == auth.RoleAdmin {
+			var admin models.Admin
+			if err := db.DB.First(&admin, subjectID).Error; err != nil || !admin.Active {
+				return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"Account deactivated", "This admin account has been deactivated"})
+			}
+			return finishAdminLogin(c, &admin)
+		}
+
+		var operator models.Operator
+		if err := db.DB.First(&operator, subjectID).Error; err != nil || !operator.Active {
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"Account deactivated", "This operator account has been deactivated"})
+		}
+		return finishOperatorLogin(c, &operator)
+	}
+}
+
+// MFAEnrollmentGuard menolak akses staff yang belum mengaktifkan MFA saat admin
+// mewajibkannya. Route enrollment MFA, ganti password dan logout harus didaftarkan
+// sebelum middleware ini.
+func MFAEnrollmentGuard(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// API key tidak punya faktor kedua; scope-nya dicek oleh RequirePermission
+		if _, ok := auth.APIKeyPrincipalFrom(c); ok {
+			return c.Next()
+		}
+
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid token claims"})
+		}
+
+		state, err := loadMFAState(role, principal.SubjectID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+		}
+
+		required, err := mfaEnrollmentRequired(state.MFAEnabled)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot load settings", err.Error()})
+		}
+		if required {
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+				Message: "MFA enrollment required",
+				Error:   "You must enable two-factor authentication before continuing",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// resetMFA mematikan MFA akun dan menghapus semua recovery code-nya
+func resetMFA(principal *auth.Principal, role string, subjectID uint, action string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(staffModel(role)).Where("id = ?", subjectID).Updates(map[string]interface{}{
+			"mfa_enabled":   false,
+			"mfa_secret":    "",
+			"mfa_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role = ? AND subject_id = ?", role, subjectID).
+			Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, action, role, subjectID, fiber.Map{})
+	})
+}
+
+// ResetStaffMFA godoc
+// @Summary Reset a staff member's MFA
+// @Description Admin-only: turn off MFA for an operator or admin who lost their authenticator. They must enroll again on next login if MFA is mandatory.
+// @Tags admin
+// @Produce json
+// @Param id path int true "Operator or admin ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators/{id}/mfa/reset [put]
+// @Router /admin/admins/{id}/mfa/reset [put]
+func ResetStaffMFA(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal, ok := auth.PrincipalFrom(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+		}
+
+		id, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid account ID"})
+		}
+
+		if _, err := loadMFAState(role, uint(id)); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{role + " not found", "No " + role + " with the given ID"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot reset MFA", err.Error()})
+		}
+
+		if err := resetMFA(principal, role, uint(id), role+".mfa.reset"); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot reset MFA", err.Error()})
+		}
+
+		return c.JSON(SuccessResponse{Message: "MFA reset"})
+	}
+}
+
+// GetMFASetting godoc
+// @Summary Get the staff MFA requirement
+// @Description Admin-only: whether every operator and admin must use MFA
+// @Tags admin
+// @Produce json
+// @Success 200 {object} MFASettingResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/settings/mfa [get]
+func GetMFASetting(c *fiber.Ctx) error {
+	required, err := models.SettingBool(db.DB, models.SettingMFARequired)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot load settings", err.Error()})
+	}
+	return c.JSON(MFASettingResponse{Required: required})
+}
+
+// UpdateMFASetting godoc
+// @Summary Require MFA for all staff
+// @Description Admin-only: when enabled, operators and admins without MFA can only enroll, change their password or log out until they enable it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param setting body validators.MFASettingInput true "MFA requirement"
+// @Success 200 {object} MFASettingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/settings/mfa [put]
+func UpdateMFASetting(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.MFASettingInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := models.SetSetting(tx, models.SettingMFARequired, strconv.FormatBool(*data.Required)); err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "setting.update", "setting", 0, fiber.Map{models.SettingMFARequired: *data.Required})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update settings", err.Error()})
+	}
+
+	return c.JSON(MFASettingResponse{Required: *data.Required})
+}
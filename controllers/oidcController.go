@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/oidc"
+	"gorm.io/gorm"
+)
+
+var errOIDCEmailNotVerified = errors.New("the identity provider did not return a verified email address")
+
+// OIDCLogin godoc
+// @Summary Start OpenID Connect login
+// @Description Redirect the customer to the configured identity provider (authorization code flow with PKCE)
+// @Tags auth
+// @Success 302
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/oidc/login [get]
+func OIDCLogin(c *fiber.Ctx) error {
+	client, err := oidc.Default()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"OIDC login unavailable", err.Error()})
+	}
+
+	state, err := oidc.NewState()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not start login", err.Error()})
+	}
+	nonce, err := oidc.NewState()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not start login", err.Error()})
+	}
+	verifier, err := oidc.NewPKCEVerifier()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not start login", err.Error()})
+	}
+
+	redirect, err := client.AuthCodeURL(c.UserContext(), state, nonce, oidc.PKCEChallenge(verifier))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not reach identity provider", err.Error()})
+	}
+
+	if err := auth.SetOIDCState(c, auth.OIDCState{State: state, Nonce: nonce, PKCEVerifier: verifier}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not start login", err.Error()})
+	}
+
+	return c.Redirect(redirect, fiber.StatusFound)
+}
+
+// OIDCCallback godoc
+// @Summary Finish OpenID Connect login
+// @Description Callback from the identity provider: validates state, exchanges the code, verifies the ID token, links or creates the customer by verified email, then sets the same session cookies as /api/login and redirects to the frontend
+// @Tags auth
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 302
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/auth/oidc/callback [get]
+func OIDCCallback(c *fiber.Ctx) error {
+	client, err := oidc.Default()
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"OIDC login unavailable", err.Error()})
+	}
+
+	// State selalu dibaca (dan cookie-nya dihapus) lebih dulu agar tidak bisa dipakai ulang
+	state, stateErr := auth.TakeOIDCState(c)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Login was not completed", providerErr + ": " + c.Query("error_description")})
+	}
+	if stateErr != nil || subtle.ConstantTimeCompare([]byte(state.State), []byte(c.Query("state"))) != 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Login was not completed", "Invalid or expired login state, please try again"})
+	}
+	code := c.Query("code")
+	if code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Login was not completed", "Missing authorization code"})
+	}
+
+	claims, err := client.Exchange(c.UserContext(), code, state.PKCEVerifier, state.Nonce)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Login failed", err.Error()})
+	}
+
+	user, takenOver, err := linkOIDCUser(client.Issuer(), claims)
+	if errors.Is(err, errOIDCEmailNotVerified) {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"Login failed", err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not login", err.Error()})
+	}
+	if user.Suspended() {
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{"Account suspended", "This account has been suspended"})
+	}
+
+	// Akun lokal dengan email belum terverifikasi mungkin dibuat orang lain memakai
+	// email ini; sesi lamanya dicabut setelah pemilik email sebenarnya masuk
+	if takenOver {
+		if err := auth.RevokeAllSessions(auth.RoleUser, uint(user.ID)); err != nil {
+			log.Printf("Failed to revoke sessions of user %d after OIDC link: %v\n", user.ID, err)
+		}
+	}
+
+	// Sesi yang sama dengan /api/login: cookie "jwt" berumur pendek plus refresh token
+	if _, err := auth.StartSession(c, auth.RoleUser, uint(user.ID)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Could not login", err.Error()})
+	}
+
+	return c.Redirect(frontendURL("/"), fiber.StatusFound)
+}
+
+// linkOIDCUser mencari user untuk identitas OIDC. Identitas yang sudah tertaut
+// langsung dipakai; jika belum, user ditautkan berdasarkan email yang sudah
+// diverifikasi provider atau dibuat baru. takenOver bernilai true jika akun lokal
+// yang emailnya belum terverifikasi diambil alih: password-nya dihapus karena
+// bisa jadi dibuat oleh orang lain sebelum pemilik email mendaftar.
+func linkOIDCUser(issuer string, claims *oidc.IDTokenClaims) (*models.User, bool, error) {
+	var user models.User
+	takenOver := false
+	now := time.Now()
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("issuer = ? AND subject = ?", issuer, claims.Subject).First(&identity).Error
+		if err == nil {
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{"last_login_at": now, "email": claims.Email}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if claims.Email == "" || !claims.EmailVerified {
+			return errOIDCEmailNotVerified
+		}
+
+		err = tx.Where("email = ?", claims.Email).First(&user).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			user = models.User{
+				Email:           claims.Email,
+				Username:        oidcUsername(claims),
+				EmailVerifiedAt: &now,
+			}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !user.EmailVerified():
+			takenOver = true
+			if err := tx.Model(&user).Updates(map[string]interface{}{"email_verified_at": now, "password": nil}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:      user.ID,
+			Issuer:      issuer,
+			Subject:     claims.Subject,
+			Email:       claims.Email,
+			LastLoginAt: now,
+		}).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &user, takenOver, nil
+}
+
+// oidcUsername memilih username awal dari claim provider
+func oidcUsername(claims *oidc.IDTokenClaims) string {
+	for _, name := range []string{claims.PreferredUsername, claims.Name} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return strings.SplitN(claims.Email, "@", 2)[0]
+}
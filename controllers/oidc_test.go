@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/oidc"
+	"github.com/raihan1405/go-restapi/oidc/oidctest"
+	"gorm.io/gorm"
+)
+
+const testOIDCRedirect = "http://localhost:8080/api/auth/oidc/callback"
+
+// newMockOIDC menjalankan identity provider tiruan dan memasangnya sebagai oidc.Default
+func newMockOIDC(t *testing.T) (*oidctest.Provider, *oidc.Client) {
+	t.Helper()
+	provider := oidctest.NewProvider()
+	client := oidc.New(oidc.Config{
+		Issuer:       provider.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  testOIDCRedirect,
+	}, provider.HTTPClient())
+	oidc.SetDefault(client)
+	t.Cleanup(func() {
+		oidc.SetDefault(nil)
+		provider.Close()
+	})
+	return provider, client
+}
+
+// mockOIDCClaims login sebagai user di provider tiruan dan mengembalikan claim ID token yang sudah divalidasi
+func mockOIDCClaims(t *testing.T, provider *oidctest.Provider, client *oidc.Client, user oidctest.User) *oidc.IDTokenClaims {
+	t.Helper()
+	ctx := context.Background()
+	verifier, _ := oidc.NewPKCEVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state", "nonce", oidc.PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	provider.SetUser(user)
+	code, _, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	claims, err := client.Exchange(ctx, code, verifier, "nonce")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	return claims
+}
+
+// seedOIDCUser membuat customer lokal dengan password; verified menentukan status emailnya
+func seedOIDCUser(t *testing.T, conn *gorm.DB, email string, verified bool) models.User {
+	t.Helper()
+	now := time.Now()
+	user := models.User{Email: email, Username: "local", Password: []byte("local-password-hash")}
+	if verified {
+		user.EmailVerifiedAt = &now
+	}
+	if err := conn.Create(&user).Error; err != nil {
+		t.Fatalf("cannot seed user: %v", err)
+	}
+	return user
+}
+
+func cleanupOIDCUsers(t *testing.T, conn *gorm.DB, suffix string) {
+	t.Cleanup(func() {
+		var ids []int
+		conn.Unscoped().Model(&models.User{}).Where("email LIKE ?", "%-"+suffix+"@example.test").Pluck("id", &ids)
+		if len(ids) == 0 {
+			return
+		}
+		conn.Where("user_id IN ?", ids).Delete(&models.UserIdentity{})
+		conn.Where("role = ? AND subject_id IN ?", auth.RoleUser, ids).Delete(&models.Session{})
+		conn.Where("role = ? AND subject_id IN ?", auth.RoleUser, ids).Delete(&models.RefreshToken{})
+		conn.Unscoped().Where("id IN ?", ids).Delete(&models.User{})
+	})
+}
+
+func TestLinkOIDCUser(t *testing.T) {
+	conn := openTestDB(t)
+	provider, client := newMockOIDC(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	cleanupOIDCUsers(t, conn, suffix)
+	email := func(name string) string { return name + "-" + suffix + "@example.test" }
+
+	t.Run("new user", func(t *testing.T) {
+		claims := mockOIDCClaims(t, provider, client, oidctest.User{Subject: "new-" + suffix, Email: email("new"), EmailVerified: true, PreferredUsername: "newbie"})
+		user, takenOver, err := linkOIDCUser(client.Issuer(), claims)
+		if err != nil {
+			t.Fatalf("linkOIDCUser: %v", err)
+		}
+		if takenOver || user.Username != "newbie" || !user.EmailVerified() || len(user.Password) != 0 {
+			t.Errorf("unexpected new user: %+v (takenOver=%v)", user, takenOver)
+		}
+
+		// Login berikutnya memakai identitas yang sudah tertaut
+		again, _, err := linkOIDCUser(client.Issuer(), claims)
+		if err != nil || again.ID != user.ID {
+			t.Fatalf("second login: user %v, err %v; want user %d", again, err, user.ID)
+		}
+		var identities int64
+		conn.Model(&models.UserIdentity{}).Where("user_id = ?", user.ID).Count(&identities)
+		if identities != 1 {
+			t.Errorf("user has %d identities, want 1", identities)
+		}
+	})
+
+	t.Run("link verified account", func(t *testing.T) {
+		local := seedOIDCUser(t, conn, email("verified"), true)
+		claims := mockOIDCClaims(t, provider, client, oidctest.User{Subject: "verified-" + suffix, Email: local.Email, EmailVerified: true})
+		user, takenOver, err := linkOIDCUser(client.Issuer(), claims)
+		if err != nil {
+			t.Fatalf("linkOIDCUser: %v", err)
+		}
+		if user.ID != local.ID || takenOver {
+			t.Fatalf("got user %d takenOver=%v, want existing user %d without take-over", user.ID, takenOver, local.ID)
+		}
+		var reloaded models.User
+		conn.First(&reloaded, local.ID)
+		if string(reloaded.Password) != "local-password-hash" {
+			t.Errorf("password of a verified account must be kept")
+		}
+	})
+
+	t.Run("take over unverified account", func(t *testing.T) {
+		local := seedOIDCUser(t, conn, email("unverified"), false)
+		claims := mockOIDCClaims(t, provider, client, oidctest.User{Subject: "unverified-" + suffix, Email: local.Email, EmailVerified: true})
+		user, takenOver, err := linkOIDCUser(client.Issuer(), claims)
+		if err != nil {
+			t.Fatalf("linkOIDCUser: %v", err)
+		}
+		if user.ID != local.ID || !takenOver {
+			t.Fatalf("got user %d takenOver=%v, want take-over of user %d", user.ID, takenOver, local.ID)
+		}
+		var reloaded models.User
+		conn.First(&reloaded, local.ID)
+		if len(reloaded.Password) != 0 || !reloaded.EmailVerified() {
+			t.Errorf("taken over account must lose its password and become verified: %+v", reloaded)
+		}
+	})
+
+	t.Run("unverified provider email", func(t *testing.T) {
+		local := seedOIDCUser(t, conn, email("unconfirmed"), true)
+		claims := mockOIDCClaims(t, provider, client, oidctest.User{Subject: "unconfirmed-" + suffix, Email: local.Email, EmailVerified: false})
+		if _, _, err := linkOIDCUser(client.Issuer(), claims); !errors.Is(err, errOIDCEmailNotVerified) {
+			t.Fatalf("linkOIDCUser error = %v, want errOIDCEmailNotVerified", err)
+		}
+	})
+}
+
+// TestOIDCLoginFlow menjalankan OIDCLogin dan OIDCCallback terhadap provider tiruan
+func TestOIDCLoginFlow(t *testing.T) {
+	conn := openTestDB(t)
+	provider, _ := newMockOIDC(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	cleanupOIDCUsers(t, conn, suffix)
+
+	app := fiber.New()
+	app.Get("/api/auth/oidc/login", OIDCLogin)
+	app.Get("/api/auth/oidc/callback", OIDCCallback)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/login", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("login status = %d, want 302", resp.StatusCode)
+	}
+	var stateCookie *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Value != "" {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil {
+		t.Fatal("login did not set the OIDC state cookie")
+	}
+
+	provider.SetUser(oidctest.User{Subject: "flow-" + suffix, Email: "flow-" + suffix + "@example.test", EmailVerified: true, Name: "Flow"})
+	code, state, err := provider.Authorize(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	callback := func(state string) *http.Response {
+		query := url.Values{"code": {code}, "state": {state}}
+		req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback?"+query.Encode(), nil)
+		req.AddCookie(stateCookie)
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	// State yang tidak cocok ditolak sebelum code ditukar
+	if resp := callback("forged-state"); resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("callback with forged state status = %d, want 400", resp.StatusCode)
+	}
+	if provider.TokenRequests != 0 {
+		t.Fatalf("code was exchanged despite a forged state")
+	}
+
+	resp = callback(state)
+	if resp.StatusCode != fiber.StatusFound {
+		t.Fatalf("callback status = %d, want 302", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Location"), frontendURL("/")) {
+		t.Errorf("callback redirected to %q", resp.Header.Get("Location"))
+	}
+
+	var user models.User
+	if err := conn.Where("email = ?", "flow-"+suffix+"@example.test").First(&user).Error; err != nil {
+		t.Fatalf("callback did not create the user: %v", err)
+	}
+	var sessions int64
+	conn.Model(&models.Session{}).Where("role = ? AND subject_id = ?", auth.RoleUser, user.ID).Count(&sessions)
+	if sessions != 1 {
+		t.Errorf("user has %d sessions, want 1", sessions)
+	}
+}
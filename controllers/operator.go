@@ -1,28 +1,24 @@
 package controllers
 
 import (
-	"strconv"
-	"time"
 	"log"
-	"fmt"
-	
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
 	"github.com/raihan1405/go-restapi/models"
 	"github.com/raihan1405/go-restapi/validators"
-	// "golang.org/x/crypto/bcrypt"
-	"os"
 )
 
-var operatorSecretKey = os.Getenv("JWT_SECRET_OPERATOR") // Pastikan Anda memiliki JWT_SECRET_OPERATOR di .env
-
 // LoginOperatorResponse defines the structure of a successful login response for operator
 type LoginOperatorResponse struct {
 	Message string        `json:"message"`
 	Token   string        `json:"token"`
+	Tokens  TokenResponse `json:"tokens"`
 	Operator OperatorInfo `json:"operator"`
+	MustChangePassword bool `json:"must_change_password"`
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required"`
 }
 
 type OperatorInfo struct {
@@ -36,33 +32,20 @@ type OperatorInfo struct {
 
 // LoginOperator godoc
 // @Summary Log in an operator
-// @Description Log in an operator with the provided Operator ID and return operator data
+// @Description Log in an operator with the provided Operator ID and password and return operator data
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param login body validators.OperatorLoginInput true "Operator login details"
-// @Success 200 {object} LoginOperatorResponse
+// @Param X-Auth-Mode header string false "Set to token to receive tokens in the JSON body without cookies"
+// @Success 200 {object} LoginOperatorResponse "Logged in, or MFAChallengeResponse when the operator has MFA enabled"
 // @Failure 400 {object} ErrorResponse
-// @Failure 404 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/operator/login [post]
 func LoginOperator(c *fiber.Ctx) error {
-    // Define multiple secret keys
-    mapKey := map[string]string{
-        "user":    os.Getenv("JWT_SECRET"),
-        "operator": os.Getenv("JWT_SECRET_OPERATOR"),
-    }
-
-    // Check if the operator secret key is set
-    operatorSecretKey, exists := mapKey["operator"]
-    if !exists || operatorSecretKey == "" {
-        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Message: "Server configuration error",
-            Error:   "JWT_SECRET_OPERATOR is not set",
-        })
-    }
-
     var data validators.OperatorLoginInput
 
     // Parse JSON body
@@ -81,60 +64,66 @@ func LoginOperator(c *fiber.Ctx) error {
         })
     }
 
-    // Find operator by OperatorID
+    // Akun atau IP yang terlalu sering gagal login ditolak sebelum password dicek
+    if err := auth.CheckLogin(c, auth.RoleOperator, data.OperatorID); err != nil {
+        return loginThrottleResponse(c, err)
+    }
+
+    // Find operator by OperatorID and verify the password. Unknown IDs and wrong
+    // passwords get the same response so the ID cannot be probed.
     var operator models.Operator
-    if err := db.DB.Where("operator_id = ?", data.OperatorID).First(&operator).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-            Message: "Operator not found",
-            Error:   "No operator with the given Operator ID",
+    err := db.DB.Where("operator_id = ?", data.OperatorID).First(&operator).Error
+    if !verifyPassword(operator.Password, data.Password) || err != nil {
+        recordLoginFailure(c, auth.RoleOperator, data.OperatorID)
+        return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+            Message: "Invalid credentials",
+            Error:   "Incorrect Operator ID or password",
         })
     }
 
-    // If you want to use password for operator, add verification here
-    /*
-        if err := bcrypt.CompareHashAndPassword([]byte(operator.Password), []byte(data.Password)); err != nil {
-            return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
-                Message: "Incorrect Operator ID or Password",
-                Error:   "Authentication failed",
-            })
-        }
-    */
-
-    // Create JWT claims
-    claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-        Subject:   strconv.Itoa(int(operator.ID)),
-        ExpiresAt: time.Now().Add(time.Hour * 24).Unix(), // Token valid for 24 hours
-    })
+    // Deactivated accounts are blocked even with the right password
+    if !operator.Active {
+        return c.Status(fiber.StatusForbidden).JSON(models.ErrorResponse{
+            Message: "Account deactivated",
+            Error:   "This operator account has been deactivated",
+        })
+    }
+
+    // Operator dengan MFA aktif harus menyelesaikan langkah kedua
+    if operator.MFAEnabled {
+        return mfaChallengeResponse(c, auth.RoleOperator, operator.ID, operator.MFAChallengeCounter)
+    }
 
-    // Set the key ID for the token
-    claims.Header["kid"] = "operator"
+    return finishOperatorLogin(c, &operator)
+}
 
-    // Sign the token with the selected secret key
-    token, err := claims.SignedString([]byte(operatorSecretKey))
+// finishOperatorLogin memulai sesi operator dan mengirim response login
+func finishOperatorLogin(c *fiber.Ctx, operator *models.Operator) error {
+    mfaEnrollmentRequired, err := mfaEnrollmentRequired(operator.MFAEnabled)
     if err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-            Message: "Could not generate token",
+            Message: "Could not login",
             Error:   err.Error(),
         })
     }
 
-    // Set cookie with the JWT token
-    cookie := fiber.Cookie{
-        Name:     "jwt_operator",
-        Value:    token,
-        Expires:  time.Now().Add(time.Hour * 24),
-        HTTPOnly: true,
-        Secure:   false, // Set to true if using HTTPS
-        SameSite: "Lax",
-        Path:     "/",
+    // Penghitung login gagal baru direset setelah semua faktor lolos
+    recordLoginSuccess(auth.RoleOperator, operator.OperatorID)
+
+    // Start a session: short-lived "jwt_operator" access cookie plus a rotating refresh token
+    pair, err := auth.StartSession(c, auth.RoleOperator, operator.ID)
+    if err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+            Message: "Could not generate token",
+            Error:   err.Error(),
+        })
     }
-    c.Cookie(&cookie)
-    log.Printf("jwt_operator cookie set: %s\n", token)
 
     // Return success response
     return c.JSON(LoginOperatorResponse{
         Message: "Login successful",
-        Token:   token,
+        Token:   pair.AccessToken,
+        Tokens:  newTokenResponse(c, pair),
         Operator: OperatorInfo{
             ID:         operator.ID,
             OperatorID: operator.OperatorID,
@@ -142,23 +131,16 @@ func LoginOperator(c *fiber.Ctx) error {
             Email:      operator.Email,
             Phone:      operator.Phone,
         },
+        MustChangePassword:    operator.MustChangePassword,
+        MFAEnrollmentRequired: mfaEnrollmentRequired,
     })
 }
 
 func LogoutOperator(c *fiber.Ctx) error {
-    // Hapus cookie jwt_operator dengan mengatur expired date ke masa lalu
-	fmt.Println("LogoutOperator called")
-
-    cookie := fiber.Cookie{
-        Name:     "jwt_operator",
-        Value:    "",
-        Expires:  time.Now().Add(-time.Hour), // Set expired
-        HTTPOnly: true,
-        Secure:   false, // Set ke true jika menggunakan HTTPS di produksi
-        SameSite: "Lax",
-		Path:     "/",
+    // Cabut refresh token dan hapus cookie sesi operator
+    if err := auth.EndSession(c, auth.RoleOperator); err != nil {
+        log.Printf("Failed to revoke operator refresh token: %v\n", err)
     }
-    c.Cookie(&cookie)
 
     return c.JSON(map[string]interface{}{"message": "Logout successful"})
 }
@@ -167,9 +149,9 @@ func GetAllProductsOperator(c *fiber.Ctx) error {
 	var products []models.Product
 
 	// Retrieve all products from the database
-	if err := db.DB.Find(&products).Error; err != nil {
+	if err := db.DB.Preload("Category").Find(&products).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve products"})
 	}
 
-	return c.JSON(products)
+	return c.JSON(dto.FromProducts(products, auth.RoleOperator))
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/mailer"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// passwordResetTTL adalah masa berlaku link reset password
+	passwordResetTTL = time.Hour
+	// passwordResetInterval membatasi seberapa sering email reset dikirim ke satu akun
+	passwordResetInterval = time.Minute
+)
+
+var errInvalidResetToken = errors.New("invalid or expired reset token")
+
+// forgotPasswordMessage dikirim untuk setiap permintaan agar keberadaan akun tidak bocor
+const forgotPasswordMessage = "If an account with that email exists, a password reset link has been sent"
+
+// sendPasswordResetEmail membuat token reset baru, membatalkan token lama yang
+// belum dipakai, lalu mengirim link-nya lewat mailer.
+func sendPasswordResetEmail(user *models.User) error {
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", user.ID).
+			Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordResetToken{
+			UserID:    user.ID,
+			TokenHash: auth.HashToken(token),
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	link := frontendURL("/reset-password?token=" + url.QueryEscape(token))
+	return mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Hi " + user.Username + ",\n\n" +
+			"We received a request to reset your password. Open the link below to choose a new one:\n\n" +
+			link + "\n\n" +
+			"The link expires in 1 hour and can be used once. If you did not request a reset, you can ignore this email.",
+	})
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email a single-use password reset link. The response is the same whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param forgot body validators.ForgotPasswordInput true "Account email"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/password/forgot [post]
+func ForgotPassword(c *fiber.Ctx) error {
+	var data validators.ForgotPasswordInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var user models.User
+	if err := db.DB.Where("email = ?", data.Email).First(&user).Error; err != nil {
+		return c.JSON(SuccessResponse{Message: forgotPasswordMessage})
+	}
+
+	var recent int64
+	db.DB.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND created_at > ?", user.ID, time.Now().Add(-passwordResetInterval)).
+		Count(&recent)
+	if recent == 0 {
+		if err := sendPasswordResetEmail(&user); err != nil {
+			log.Printf("Failed to send password reset email to user %d: %v\n", user.ID, err)
+		}
+	}
+
+	return c.JSON(SuccessResponse{Message: forgotPasswordMessage})
+}
+
+// ResetPassword godoc
+// @Summary Reset password with a token
+// @Description Set a new password using the token from the reset email. Every existing session of the account is logged out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body validators.ResetPasswordInput true "Reset token and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/password/reset [post]
+func ResetPassword(c *fiber.Ctx) error {
+	var data validators.ResetPasswordInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	newPassword, err := bcrypt.GenerateFromPassword([]byte(data.NewPassword), 14)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot hash new password", err.Error()})
+	}
+
+	var userID int
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		var record models.PasswordResetToken
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashToken(data.Token), time.Now()).
+			First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		// Update bersyarat agar token yang dipakai bersamaan hanya berhasil sekali
+		now := time.Now()
+		result := tx.Model(&models.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", record.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInvalidResetToken
+		}
+
+		// Link reset sampai ke inbox user, jadi emailnya sekaligus terbukti valid
+		updates := map[string]interface{}{
+			"password":          newPassword,
+			"email_verified_at": gorm.Expr("COALESCE(email_verified_at, ?)", now),
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", record.UserID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		userID = record.UserID
+		return nil
+	})
+	if errors.Is(err, errInvalidResetToken) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Password reset failed", err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Password reset failed", err.Error()})
+	}
+
+	// Password lama mungkin sudah bocor, jadi semua sesi yang ada harus login ulang
+	if err := auth.RevokeAllSessions(auth.RoleUser, uint(userID)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke sessions", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Password has been reset; please log in again"})
+}
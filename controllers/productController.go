@@ -1,18 +1,20 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v4"
-	//"github.com/golang-jwt/jwt/v4" // Menggunakan jwt dari golang-jwt/jwt/v4
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
 	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/search"
 	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
 )
 
 // AddProduct godoc
@@ -22,31 +24,17 @@ import (
 // @Accept json
 // @Produce json
 // @Param product body validators.AddProductInput true "Product details"
-// @Success 200 {object} models.Product
+// @Success 201 {object} dto.ProductResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/products [post]
 func AddProduct(c *fiber.Ctx) error {
-	// Mendapatkan user dari context (yang di-set oleh middleware JWT)
-	userInterface := c.Locals("user")
-	if userInterface == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	user, ok := userInterface.(*jwt.Token)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user token"})
-	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
+	// Mendapatkan operator dari principal yang di-set oleh auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-	}
-
-	operatorID, ok := claims["sub"].(string)
-	if !ok || operatorID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid operator ID in token"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
+	operatorID := principal.Subject()
 
 	var data validators.AddProductInput
 
@@ -62,6 +50,14 @@ func AddProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": err.Error()})
 	}
 
+	category, err := productCategory(db.DB, data.CategoryID, data.Category)
+	if errors.Is(err, errUnknownCategory) {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": "Unknown category"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve category"})
+	}
+
 	// Set status based on quantity
 	status := data.Quantity > 0
 
@@ -72,7 +68,7 @@ func AddProduct(c *fiber.Ctx) error {
 		Price:       int(data.Price),
 		Status:      status,
 		Quantity:    data.Quantity,
-		Category:    data.Category, // Menyimpan Category
+		CategoryID:  &category.ID,
 		OperatorID:  operatorID,    // Menyimpan OperatorID
 	}
 
@@ -95,26 +91,82 @@ func AddProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Failed to record product entry"})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(product)
+	// Produk baru langsung bisa dicari tanpa menunggu rebuild index
+	product.Category = category
+	search.Default.Put(product)
+
+	return c.Status(fiber.StatusCreated).JSON(dto.FromProduct(&product, principal.Role))
 }
 
 // GetAllProducts godoc
-// @Summary Get all products
-// @Description Get a list of all products
+// @Summary Search the product catalog
+// @Description Search, filter, sort and paginate products. Use page for numbered pages or pass next_cursor from the previous response as cursor for stable infinite scrolling.
 // @Tags product
 // @Produce json
-// @Success 200 {array} models.Product
+// @Param q query string false "Keyword matched against product and brand names (prefix match per word)"
+// @Param category query string false "Category ID or slug; products of its subcategories are included"
+// @Param brand query string false "Brand name; several brands may be separated by commas"
+// @Param min_price query int false "Minimum price"
+// @Param max_price query int false "Maximum price"
+// @Param in_stock query bool false "Only products with stock left"
+// @Param sort query string false "id, name, brand, price or quantity (default id)"
+// @Param order query string false "asc or desc (default asc)"
+// @Param limit query int false "Results per page (default 20, max 100)"
+// @Param page query int false "Page number (default 1); cannot be combined with cursor"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} ProductListResponse
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/products [get]
 func GetAllProducts(c *fiber.Ctx) error {
-	var products []models.Product
+	query, err := parseProductQuery(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": err.Error()})
+	}
+
+	if query.Category != "" {
+		query.CategoryIDs, err = categoryFilter(db.DB, query.Category, viewerRole(c))
+		if errors.Is(err, errUnknownCategory) {
+			return c.Status(fiber.StatusNotFound).JSON(map[string]interface{}{"error": "Category not found"})
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve category"})
+		}
+	}
 
-	// Retrieve all products from the database
-	if err := db.DB.Find(&products).Error; err != nil {
+	// Query berfilter dipakai dua kali: untuk total dan untuk halaman yang diminta
+	filtered := query.filter(db.DB.Model(&models.Product{})).Session(&gorm.Session{})
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve products"})
 	}
 
-	return c.JSON(products)
+	var products []models.Product
+	if err := query.paginate(filtered).Preload("Category").Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve products"})
+	}
+
+	response := ProductListResponse{
+		Total: total,
+		Limit: query.Limit,
+		Page:  query.Page,
+	}
+	if len(products) > query.Limit {
+		products = products[:query.Limit]
+		response.NextCursor = query.nextCursor(&products[len(products)-1])
+	}
+	response.Products = dto.FromProducts(products, viewerRole(c))
+
+	return c.JSON(response)
+}
+
+// viewerRole mengembalikan role pemanggil untuk menentukan field yang boleh dilihat
+func viewerRole(c *fiber.Ctx) string {
+	if principal, ok := auth.PrincipalFrom(c); ok {
+		return principal.Role
+	}
+	return auth.RoleUser
 }
 
 // EditProduct godoc
@@ -125,36 +177,15 @@ func GetAllProducts(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Product ID"
 // @Param product body validators.EditProductInput true "Product details"
-// @Success 200 {object} models.Product
+// @Success 200 {object} dto.ProductResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/products/{id} [put]
 func EditProduct(c *fiber.Ctx) error {
-	// Get token from cookie
-	cookie := c.Cookies("jwt_operator")
-
-	// Parse the token to validate it and extract claims
-	token, err := jwt.ParseWithClaims(cookie, &jwt.StandardClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Use the appropriate signing key based on the kid
-		keyID, ok := token.Header["kid"].(string)
-		if !ok {
-			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorClaimsInvalid)
-		}
-		fmt.Println(keyID)
-
-		switch keyID {
-		case "operator":
-			return []byte(os.Getenv("JWT_SECRET_OPERATOR")), nil
-		case "user":
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		default:
-			return nil, jwt.NewValidationError("invalid kid", jwt.ValidationErrorClaimsInvalid)
-		}
-	})
-
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(map[string]interface{}{"error": "unauthenticated"})
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
 
 	// Get product ID from the URL parameter
@@ -179,27 +210,49 @@ func EditProduct(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": "Quantity cannot be negative"})
 	}
 
-	// Find product by ID
+	category, err := productCategory(db.DB, data.CategoryID, data.Category)
+	if errors.Is(err, errUnknownCategory) {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": "Unknown category"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve category"})
+	}
+
+	// Produk dikunci selama edit agar reservasi checkout di antara baca dan simpan
+	// tidak tertimpa; selisih stok dicatat sebagai ProductIn/ProductOut
 	var product models.Product
-	if err := db.DB.First(&product, id).Error; err != nil {
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := lockProduct(tx, id)
+		if err != nil {
+			return err
+		}
+		product = *locked
+		delta := data.Quantity - product.Quantity
+
+		// Update product details
+		product.ProductName = data.ProductName
+		product.BrandName = data.BrandName
+		product.CategoryID = &category.ID
+		product.Price = int(data.Price)
+		product.Quantity = data.Quantity
+		product.Status = data.Quantity > 0
+
+		if err := tx.Save(&product).Error; err != nil {
+			return err
+		}
+		return recordStockAdjustment(tx, product.ID, delta, principal.Subject())
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.Status(fiber.StatusNotFound).JSON(map[string]interface{}{"error": "Product not found"})
 	}
-
-	// Update product details
-	product.ProductName = data.ProductName
-	product.BrandName = data.BrandName
-	product.Category = data.Category
-	product.Price = int(data.Price)
-	product.Quantity = data.Quantity
-	product.Status = data.Quantity > 0
-
-	// Save the updated product
-	if err := db.DB.Save(&product).Error; err != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot update product"})
 	}
+	product.Category = category
+	search.Default.Put(product)
 
 	// Return the updated product
-	return c.JSON(product)
+	return c.JSON(dto.FromProduct(&product, viewerRole(c)))
 }
 
 func GenerateProductReport(c *fiber.Ctx) error {
@@ -211,7 +264,7 @@ func GenerateProductReport(c *fiber.Ctx) error {
 
 	// Mendapatkan data produk dari database
 	var product models.Product
-	if err := db.DB.First(&product, id).Error; err != nil {
+	if err := db.DB.Preload("Category").First(&product, id).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(map[string]interface{}{"error": "Product not found"})
 	}
 
@@ -248,7 +301,7 @@ func GenerateProductReport(c *fiber.Ctx) error {
 	report := models.ProductReport{
 		ProductName:       product.ProductName,
 		ProductID:         fmt.Sprintf("%d", product.ID),
-		Category:          product.Category,
+		Category:          product.CategoryName(),
 		BrandName:         product.BrandName,
 		InitialStock:      initialStock,
 		FirstInStock:      firstInStock,
@@ -260,26 +313,12 @@ func GenerateProductReport(c *fiber.Ctx) error {
 }
 
 func HandleProductOut(c *fiber.Ctx) error {
-	// Step 1: Get user data from the context (authentication)
-	userInterface := c.Locals("user")
-	if userInterface == nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
-	}
-
-	user, ok := userInterface.(*jwt.Token)
-	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid user token"})
-	}
-
-	claims, ok := user.Claims.(jwt.MapClaims)
+	// Mendapatkan operator dari principal yang di-set oleh auth.RequireRole
+	principal, ok := auth.PrincipalFrom(c)
 	if !ok {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid token claims"})
-	}
-
-	operatorID, ok := claims["sub"].(string)
-	if !ok || operatorID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid operator ID in token"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
 	}
+	operatorID := principal.Subject()
 
 	var data struct {
 		ProductID uint `json:"productId"`
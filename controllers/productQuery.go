@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultProductLimit = 20
+	maxProductLimit     = 100
+)
+
+// ProductListResponse adalah satu halaman katalog. Total dihitung dengan filter
+// yang sama tanpa pagination; NextCursor kosong jika tidak ada halaman berikutnya.
+type ProductListResponse struct {
+	Products   []dto.ProductResponse `json:"products"`
+	Total      int64                 `json:"total"`
+	Limit      int                   `json:"limit"`
+	Page       int                   `json:"page,omitempty"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// productSortColumns adalah field sort yang diizinkan beserta kolomnya
+var productSortColumns = map[string]string{
+	"id":       "id",
+	"name":     "product_name",
+	"brand":    "brand_name",
+	"price":    "price",
+	"quantity": "quantity",
+}
+
+// productCursor menandai baris terakhir halaman sebelumnya. Sort dan order ikut
+// disimpan agar cursor tidak dipakai dengan urutan yang berbeda.
+type productCursor struct {
+	Sort  string      `json:"s"`
+	Order string      `json:"o"`
+	Value interface{} `json:"v"`
+	ID    int         `json:"id"`
+}
+
+// productQuery adalah parameter pencarian katalog yang sudah divalidasi
+type productQuery struct {
+	Keyword  string
+	Category string
+	// CategoryIDs adalah kategori yang dibuka beserta turunannya, diisi handler
+	// dari Category karena membutuhkan database
+	CategoryIDs []uint
+	Brands      []string
+	MinPrice    *int
+	MaxPrice    *int
+	InStock     bool
+	Sort        string
+	Order       string
+	Limit       int
+	Page        int
+	Cursor      *productCursor
+}
+
+// parseProductQuery membaca query string katalog
+func parseProductQuery(c *fiber.Ctx) (*productQuery, error) {
+	query := &productQuery{
+		Keyword:  strings.TrimSpace(c.Query("q")),
+		Category: strings.TrimSpace(c.Query("category")),
+		InStock:  c.QueryBool("in_stock"),
+		Sort:     c.Query("sort", "id"),
+		Order:    c.Query("order", "asc"),
+		Limit:    c.QueryInt("limit", defaultProductLimit),
+	}
+
+	// brand boleh lebih dari satu, dipisah koma
+	for _, brand := range strings.Split(c.Query("brand"), ",") {
+		if brand = strings.TrimSpace(brand); brand != "" {
+			query.Brands = append(query.Brands, brand)
+		}
+	}
+
+	for _, bound := range []struct {
+		Param  string
+		Target **int
+	}{
+		{"min_price", &query.MinPrice},
+		{"max_price", &query.MaxPrice},
+	} {
+		value := c.Query(bound.Param)
+		if value == "" {
+			continue
+		}
+		price, err := strconv.Atoi(value)
+		if err != nil || price < 0 {
+			return nil, errors.New(bound.Param + " must be a non-negative integer")
+		}
+		*bound.Target = &price
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return nil, errors.New("min_price cannot be greater than max_price")
+	}
+
+	if _, ok := productSortColumns[query.Sort]; !ok {
+		return nil, errors.New("sort must be one of id, name, brand, price or quantity")
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		return nil, errors.New("order must be asc or desc")
+	}
+	if query.Limit <= 0 || query.Limit > maxProductLimit {
+		query.Limit = defaultProductLimit
+	}
+
+	cursor := c.Query("cursor")
+	if cursor != "" && c.Query("page") != "" {
+		return nil, errors.New("use either page or cursor, not both")
+	}
+	if cursor != "" {
+		decoded, err := decodeProductCursor(cursor)
+		if err != nil || decoded.Sort != query.Sort || decoded.Order != query.Order {
+			return nil, errors.New("invalid cursor")
+		}
+		query.Cursor = decoded
+	} else {
+		query.Page = c.QueryInt("page", 1)
+		if query.Page < 1 {
+			query.Page = 1
+		}
+	}
+
+	return query, nil
+}
+
+// filter menerapkan kata kunci, kategori (termasuk subkategori), merek, rentang harga dan stok
+func (q *productQuery) filter(tx *gorm.DB) *gorm.DB {
+	terms, shortWords := fulltextTerms(q.Keyword)
+	if terms != "" {
+		tx = tx.Where("MATCH(product_name, brand_name) AGAINST (? IN BOOLEAN MODE)", terms)
+	}
+	// Kata yang tidak ada di index FULLTEXT (tv, hp) dicocokkan sebagai awalan kata dengan LIKE
+	for _, word := range shortWords {
+		tx = tx.Where("(product_name LIKE ? OR product_name LIKE ? OR brand_name LIKE ? OR brand_name LIKE ?)",
+			word+"%", "% "+word+"%", word+"%", "% "+word+"%")
+	}
+	if q.Category != "" {
+		tx = tx.Where("category_id IN ?", q.CategoryIDs)
+	}
+	if len(q.Brands) > 0 {
+		tx = tx.Where("brand_name IN ?", q.Brands)
+	}
+	if q.MinPrice != nil {
+		tx = tx.Where("price >= ?", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		tx = tx.Where("price <= ?", *q.MaxPrice)
+	}
+	if q.InStock {
+		tx = tx.Where("quantity > 0")
+	}
+	return tx
+}
+
+// paginate mengurutkan hasil dan memotongnya sesuai cursor atau nomor halaman.
+// Satu baris ekstra diambil untuk mengetahui apakah masih ada halaman berikutnya.
+func (q *productQuery) paginate(tx *gorm.DB) *gorm.DB {
+	column := productSortColumns[q.Sort]
+	direction, comparison := "ASC", ">"
+	if q.Order == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+
+	// ID sebagai pemutus seri membuat urutan stabil untuk cursor
+	if q.Cursor != nil {
+		if column == "id" {
+			tx = tx.Where("id "+comparison+" ?", q.Cursor.ID)
+		} else {
+			tx = tx.Where("("+column+" "+comparison+" ? OR ("+column+" = ? AND id "+comparison+" ?))", q.Cursor.Value, q.Cursor.Value, q.Cursor.ID)
+		}
+	}
+	if column != "id" {
+		tx = tx.Order(column + " " + direction)
+	}
+	tx = tx.Order("id " + direction).Limit(q.Limit + 1)
+
+	if q.Cursor == nil {
+		tx = tx.Offset((q.Page - 1) * q.Limit)
+	}
+	return tx
+}
+
+// nextCursor membuat cursor dari produk terakhir di halaman
+func (q *productQuery) nextCursor(product *models.Product) string {
+	var value interface{}
+	switch q.Sort {
+	case "name":
+		value = product.ProductName
+	case "brand":
+		value = product.BrandName
+	case "price":
+		value = product.Price
+	case "quantity":
+		value = product.Quantity
+	}
+
+	encoded, _ := json.Marshal(productCursor{Sort: q.Sort, Order: q.Order, Value: value, ID: product.ID})
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeProductCursor(cursor string) (*productCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	var decoded productCursor
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// fulltextMinTokenSize adalah innodb_ft_min_token_size bawaan MySQL; kata yang
+// lebih pendek tidak masuk index FULLTEXT
+const fulltextMinTokenSize = 3
+
+// fulltextStopwords adalah INFORMATION_SCHEMA.INNODB_FT_DEFAULT_STOPWORD. Kata
+// ini tidak di-index sehingga "+kata*" membuat query tidak pernah cocok.
+var fulltextStopwords = map[string]bool{
+	"a": true, "about": true, "an": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "com": true, "de": true, "en": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"la": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "what": true, "when": true, "where": true,
+	"who": true, "will": true, "with": true, "und": true, "www": true,
+}
+
+// fulltextTerms mengubah kata kunci menjadi query FULLTEXT boolean: setiap kata
+// wajib ada dan boleh berupa awalan. Operator boolean dari input dibuang. Kata
+// yang lebih pendek dari fulltextMinTokenSize atau termasuk stopword InnoDB
+// dikembalikan terpisah di shortWords untuk dicocokkan tanpa index FULLTEXT.
+func fulltextTerms(keyword string) (terms string, shortWords []string) {
+	words := strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	required := make([]string, 0, len(words))
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < fulltextMinTokenSize || fulltextStopwords[word] {
+			shortWords = append(shortWords, word)
+			continue
+		}
+		required = append(required, "+"+word+"*")
+	}
+	return strings.Join(required, " "), shortWords
+}
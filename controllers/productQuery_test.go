@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFulltextTerms(t *testing.T) {
+	for _, tc := range []struct {
+		keyword string
+		terms   string
+		short   []string
+	}{
+		{"sepatu lari", "+sepatu* +lari*", nil},
+		{"Smart TV", "+smart*", []string{"tv"}},
+		{"hp", "", []string{"hp"}},
+		{"case for hp", "+case*", []string{"for", "hp"}},
+		{"+nike -adidas \"air\"", "+nike* +adidas* +air*", nil},
+		{"  ", "", nil},
+	} {
+		terms, short := fulltextTerms(tc.keyword)
+		if terms != tc.terms || !reflect.DeepEqual(short, tc.short) {
+			t.Errorf("fulltextTerms(%q) = %q, %q; want %q, %q", tc.keyword, terms, short, tc.terms, tc.short)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// regionCacheControl mengizinkan klien menyimpan data wilayah karena jarang berubah
+const regionCacheControl = "public, max-age=86400"
+
+// listRegions mengirim anak-anak satu wilayah. parent nil berarti tidak ada
+// wilayah induk yang perlu dicek (daftar provinsi).
+func listRegions(c *fiber.Ctx, parent interface{}, parentColumn string, children interface{}) error {
+	query := db.DB.Order("id")
+	if parent != nil {
+		var count int64
+		if err := db.DB.Model(parent).Where("id = ?", c.Params("id")).Count(&count).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve regions", err.Error()})
+		}
+		if count == 0 {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"region not found", "No region with the given ID"})
+		}
+		query = query.Where(parentColumn+" = ?", c.Params("id"))
+	}
+
+	if err := query.Find(children).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve regions", err.Error()})
+	}
+
+	c.Set(fiber.HeaderCacheControl, regionCacheControl)
+	return c.JSON(children)
+}
+
+// ListProvinces godoc
+// @Summary List provinces
+// @Description List all Indonesian provinces. IDs are Kemendagri region codes.
+// @Tags region
+// @Produce json
+// @Success 200 {array} models.Province
+// @Failure 500 {object} ErrorResponse
+// @Router /api/regions/provinces [get]
+func ListProvinces(c *fiber.Ctx) error {
+	return listRegions(c, nil, "", &[]models.Province{})
+}
+
+// ListCities godoc
+// @Summary List cities of a province
+// @Description List the regencies and cities (kabupaten/kota) of a province
+// @Tags region
+// @Produce json
+// @Param id path string true "Province code, e.g. 31"
+// @Success 200 {array} models.City
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/regions/provinces/{id}/cities [get]
+func ListCities(c *fiber.Ctx) error {
+	return listRegions(c, &models.Province{}, "province_id", &[]models.City{})
+}
+
+// ListDistricts godoc
+// @Summary List districts of a city
+// @Description List the districts (kecamatan) of a regency or city
+// @Tags region
+// @Produce json
+// @Param id path string true "City code, e.g. 31.71"
+// @Success 200 {array} models.District
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/regions/cities/{id}/districts [get]
+func ListDistricts(c *fiber.Ctx) error {
+	return listRegions(c, &models.City{}, "city_id", &[]models.District{})
+}
+
+// ListVillages godoc
+// @Summary List villages of a district
+// @Description List the villages (kelurahan/desa) of a district with their postal codes. Use the village ID as village_id when saving an address.
+// @Tags region
+// @Produce json
+// @Param id path string true "District code, e.g. 31.71.01"
+// @Success 200 {array} models.Village
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/regions/districts/{id}/villages [get]
+func ListVillages(c *fiber.Ctx) error {
+	return listRegions(c, &models.District{}, "district_id", &[]models.Village{})
+}
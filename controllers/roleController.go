@@ -0,0 +1,371 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+// unknownNamesError dikembalikan saat nama role atau permission tidak ada di database
+type unknownNamesError struct {
+	Kind  string
+	Names []string
+}
+
+func (e *unknownNamesError) Error() string {
+	return "unknown " + e.Kind + ": " + strings.Join(e.Names, ", ")
+}
+
+// missingNames mengembalikan nama yang diminta tetapi tidak ditemukan
+func missingNames(requested []string, found map[string]bool) []string {
+	var missing []string
+	for _, name := range requested {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// findPermissions mengambil permission berdasarkan nama dan menolak nama yang tidak dikenal
+func findPermissions(tx *gorm.DB, names []string) ([]models.Permission, error) {
+	var perms []models.Permission
+	if err := tx.Where("name IN ?", names).Find(&perms).Error; err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, perm := range perms {
+		found[perm.Name] = true
+	}
+	if missing := missingNames(names, found); len(missing) > 0 {
+		return nil, &unknownNamesError{Kind: "permissions", Names: missing}
+	}
+	return perms, nil
+}
+
+// findRoles mengambil role berdasarkan nama beserta permission-nya
+func findRoles(tx *gorm.DB, names []string) ([]models.Role, error) {
+	var roles []models.Role
+	if err := tx.Preload("Permissions").Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, role := range roles {
+		found[role.Name] = true
+	}
+	if missing := missingNames(names, found); len(missing) > 0 {
+		return nil, &unknownNamesError{Kind: "roles", Names: missing}
+	}
+	return roles, nil
+}
+
+// rolesGrant memeriksa apakah salah satu role memberikan permission tertentu
+func rolesGrant(roles []models.Role, permission string) bool {
+	for _, role := range roles {
+		for _, perm := range role.Permissions {
+			if perm.Name == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// roleErrorResponse menerjemahkan error role/permission menjadi response
+func roleErrorResponse(c *fiber.Ctx, err error, message string) error {
+	var unknown *unknownNamesError
+	if errors.As(err, &unknown) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", unknown.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{message, err.Error()})
+}
+
+// ListPermissions godoc
+// @Summary List permissions
+// @Description Admin-only: list every permission that can be granted to a role
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.PermissionResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/permissions [get]
+func ListPermissions(c *fiber.Ctx) error {
+	var perms []models.Permission
+	if err := db.DB.Order("name").Find(&perms).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve permissions", err.Error()})
+	}
+	return c.JSON(dto.FromPermissions(perms))
+}
+
+// ListRoles godoc
+// @Summary List roles
+// @Description Admin-only: list staff roles with their permissions
+// @Tags admin
+// @Produce json
+// @Success 200 {array} dto.RoleResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/roles [get]
+func ListRoles(c *fiber.Ctx) error {
+	var roles []models.Role
+	if err := db.DB.Preload("Permissions").Order("name").Find(&roles).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve roles", err.Error()})
+	}
+	return c.JSON(dto.FromRoles(roles))
+}
+
+// CreateRole godoc
+// @Summary Create a role
+// @Description Admin-only: create a custom staff role from a set of permissions
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param role body validators.RoleInput true "Role details"
+// @Success 201 {object} dto.RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/roles [post]
+func CreateRole(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.RoleInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var count int64
+	db.DB.Model(&models.Role{}).Where("name = ?", data.Name).Count(&count)
+	if count > 0 {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Role already exists", "A role with this name already exists"})
+	}
+
+	var role models.Role
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		perms, err := findPermissions(tx, data.Permissions)
+		if err != nil {
+			return err
+		}
+
+		role = models.Role{Name: data.Name, Description: data.Description, Permissions: perms}
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "role.create", "role", role.ID, data)
+	})
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot create role")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.FromRole(&role))
+}
+
+// UpdateRole godoc
+// @Summary Update a role
+// @Description Admin-only: rename a custom role or replace its permissions. Built-in roles cannot be changed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param role body validators.RoleInput true "Role details"
+// @Success 200 {object} dto.RoleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/roles/{id} [put]
+func UpdateRole(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid role ID"})
+	}
+
+	var data validators.RoleInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var role models.Role
+	if err := db.DB.First(&role, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"role not found", "No role with the given ID"})
+	}
+	if role.BuiltIn {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Built-in roles cannot be changed"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		perms, err := findPermissions(tx, data.Permissions)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&role).Updates(map[string]interface{}{
+			"name":        data.Name,
+			"description": data.Description,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&role).Association("Permissions").Replace(perms); err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "role.update", "role", role.ID, data)
+	})
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot update role")
+	}
+
+	db.DB.Preload("Permissions").First(&role, id)
+	return c.JSON(dto.FromRole(&role))
+}
+
+// DeleteRole godoc
+// @Summary Delete a role
+// @Description Admin-only: delete a custom role and remove it from every operator and admin. Built-in roles cannot be deleted.
+// @Tags admin
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/roles/{id} [delete]
+func DeleteRole(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid role ID"})
+	}
+
+	var role models.Role
+	if err := db.DB.First(&role, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"role not found", "No role with the given ID"})
+	}
+	if role.BuiltIn {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Built-in roles cannot be deleted"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, join := range []string{"operator_roles", "admin_roles", "role_permissions"} {
+			if err := tx.Exec("DELETE FROM "+join+" WHERE role_id = ?", role.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&role).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "role.delete", "role", role.ID, fiber.Map{"name": role.Name})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot delete role", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Role deleted"})
+}
+
+// assignStaffRoles mengganti seluruh role milik satu operator atau admin
+func assignStaffRoles(c *fiber.Ctx, role string) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid account ID"})
+	}
+
+	var data validators.AssignRolesInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	account := staffModel(role)
+	if err := db.DB.First(account, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{role + " not found", "No " + role + " with the given ID"})
+	}
+
+	roles, err := findRoles(db.DB, data.Roles)
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot assign roles")
+	}
+
+	// Admin tidak boleh mencabut hak mengelola role dari dirinya sendiri
+	if role == auth.RoleAdmin && uint(id) == principal.SubjectID && !rolesGrant(roles, models.PermissionRolesManage) {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "You cannot remove the " + models.PermissionRolesManage + " permission from yourself"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Association("Roles").Replace(roles); err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, role+".roles", role, uint(id), fiber.Map{"roles": data.Roles})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot assign roles", err.Error()})
+	}
+
+	db.DB.Preload("Roles").First(account, id)
+	return c.JSON(dto.FromStaff(account))
+}
+
+// AssignOperatorRoles godoc
+// @Summary Assign roles to an operator
+// @Description Admin-only: replace the roles of an operator, e.g. ["warehouse"] for stock-only access
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Operator ID"
+// @Param roles body validators.AssignRolesInput true "Role names"
+// @Success 200 {object} dto.OperatorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators/{id}/roles [put]
+func AssignOperatorRoles(c *fiber.Ctx) error {
+	return assignStaffRoles(c, auth.RoleOperator)
+}
+
+// AssignAdminRoles godoc
+// @Summary Assign roles to an admin
+// @Description Admin-only: replace the roles of an admin
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Param roles body validators.AssignRolesInput true "Role names"
+// @Success 200 {object} dto.AdminResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins/{id}/roles [put]
+func AssignAdminRoles(c *fiber.Ctx) error {
+	return assignStaffRoles(c, auth.RoleAdmin)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/search"
+)
+
+const maxSuggestions = 10
+
+// ProductSearchResponse adalah hasil pencarian produk, urut dari yang paling relevan
+type ProductSearchResponse struct {
+	Query    string                `json:"query"`
+	Products []dto.ProductResponse `json:"products"`
+}
+
+// SuggestionResponse berisi saran pelengkap query untuk autocomplete
+type SuggestionResponse struct {
+	Query       string   `json:"query"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// SearchProducts godoc
+// @Summary Full-text product search
+// @Description Search product names, brands and categories. Tolerates typos, matches Indonesian and English word forms (sepatunya, running) and treats the last word as a prefix. Results are ranked by relevance and sales.
+// @Tags product
+// @Produce json
+// @Param q query string true "Search text, e.g. sepatu lari nik"
+// @Param limit query int false "Maximum results (default 20, max 100)"
+// @Success 200 {object} ProductSearchResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/products/search [get]
+func SearchProducts(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(map[string]interface{}{"error": "q is required"})
+	}
+	limit := c.QueryInt("limit", defaultProductLimit)
+	if limit <= 0 || limit > maxProductLimit {
+		limit = defaultProductLimit
+	}
+
+	hits := search.Default.Search(query, limit)
+	ids := make([]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ProductID
+	}
+
+	// Data produk (harga, stok) selalu diambil dari database; index hanya menentukan urutan
+	var found []models.Product
+	if len(ids) > 0 {
+		if err := db.DB.Preload("Category").Where("id IN ?", ids).Find(&found).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(map[string]interface{}{"error": "Cannot retrieve products"})
+		}
+	}
+	byID := make(map[int]models.Product, len(found))
+	for _, product := range found {
+		byID[product.ID] = product
+	}
+	products := make([]models.Product, 0, len(found))
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			products = append(products, product)
+		}
+	}
+
+	return c.JSON(ProductSearchResponse{Query: query, Products: dto.FromProducts(products, viewerRole(c))})
+}
+
+// SuggestProducts godoc
+// @Summary Autocomplete product search
+// @Description Complete the last word of a partial query using words from matching products, most popular first
+// @Tags product
+// @Produce json
+// @Param q query string true "Partial search text, e.g. sepatu la"
+// @Param limit query int false "Maximum suggestions (default and max 10)"
+// @Success 200 {object} SuggestionResponse
+// @Router /api/products/suggest [get]
+func SuggestProducts(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	limit := c.QueryInt("limit", maxSuggestions)
+	if limit <= 0 || limit > maxSuggestions {
+		limit = maxSuggestions
+	}
+
+	suggestions := search.Default.Suggest(query, limit)
+	if suggestions == nil {
+		suggestions = []string{}
+	}
+	return c.JSON(SuggestionResponse{Query: query, Suggestions: suggestions})
+}
+
+// RebuildSearchIndex godoc
+// @Summary Rebuild the product search index
+// @Description Admin-only: reload all products and sales figures into the search index. The index is also rebuilt hourly and on startup.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} search.Stats
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/search/rebuild [post]
+func RebuildSearchIndex(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	stats, err := search.Rebuild(db.DB)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot rebuild search index", err.Error()})
+	}
+
+	if err := recordAudit(db.DB, principal, "search.rebuild", "search_index", 0, fiber.Map{"products": stats.Products, "terms": stats.Terms}); err != nil {
+		log.Printf("Failed to record search index rebuild: %v\n", err)
+	}
+
+	return c.JSON(stats)
+}
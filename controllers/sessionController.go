@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+// SessionResponse mewakili satu sesi login aktif
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description List the active sessions of the logged-in account. Available under /api, /operator and /admin.
+// @Tags session
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sessions [get]
+func ListSessions(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var sessions []models.Session
+	if err := db.DB.
+		Where("role = ? AND subject_id = ? AND revoked_at IS NULL", principal.Role, principal.SubjectID).
+		Order("last_seen_at desc").
+		Find(&sessions).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve sessions", err.Error()})
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.ID == principal.SessionID,
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// RevokeSession godoc
+// @Summary Revoke one session
+// @Description Log out one of the logged-in account's sessions. Revoking the current session also clears its cookies.
+// @Tags session
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sessions/{id} [delete]
+func RevokeSession(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid session ID"})
+	}
+
+	var session models.Session
+	if err := db.DB.Where("id = ? AND role = ? AND subject_id = ? AND revoked_at IS NULL", id, principal.Role, principal.SubjectID).
+		First(&session).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"session not found", "No active session with the given ID"})
+	}
+
+	if session.ID == principal.SessionID {
+		if err := auth.EndSession(c, principal.Role); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke session", err.Error()})
+		}
+		return c.JSON(SuccessResponse{Message: "Session revoked"})
+	}
+
+	if err := auth.RevokeSession(session.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke session", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "Session revoked"})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Log out every session of the logged-in account except the current one
+// @Tags session
+// @Produce json
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/sessions [delete]
+func RevokeOtherSessions(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var ids []uint
+	if err := db.DB.Model(&models.Session{}).
+		Where("role = ? AND subject_id = ? AND id <> ? AND revoked_at IS NULL", principal.Role, principal.SubjectID, principal.SessionID).
+		Pluck("id", &ids).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke sessions", err.Error()})
+	}
+
+	for _, id := range ids {
+		if err := auth.RevokeSession(id); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke sessions", err.Error()})
+		}
+	}
+
+	return c.JSON(SuccessResponse{Message: "Other sessions revoked"})
+}
+
+// AdminRevokeAccountSessions godoc
+// @Summary Revoke every session of an account
+// @Description Admin-only: log out every session of the given user, operator or admin. The action is recorded in the audit log.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param revoke body validators.RevokeAccountSessionsInput true "Account to log out"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/sessions/revoke-all [post]
+func AdminRevokeAccountSessions(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.RevokeAccountSessionsInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	if err := db.DB.First(accountModel(data.Role), data.SubjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{data.Role + " not found", "No " + data.Role + " with the given ID"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke sessions", err.Error()})
+	}
+
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := auth.RevokeAllSessionsTx(tx, data.Role, data.SubjectID); err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, data.Role+".sessions.revoke", data.Role, data.SubjectID, fiber.Map{})
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to revoke sessions", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "All sessions revoked"})
+}
+
+// accountModel mengembalikan model GORM untuk akun dengan role tertentu
+func accountModel(role string) interface{} {
+	if role == auth.RoleUser {
+		return &models.User{}
+	}
+	return staffModel(role)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/validators"
+)
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens issued by this API, including the next key once it is pre-published
+// @Tags auth
+// @Produce json
+// @Success 200 {object} auth.JWKS
+// @Failure 500 {object} ErrorResponse
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *fiber.Ctx) error {
+	set, err := auth.PublicJWKS()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot load signing keys", err.Error()})
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(set)
+}
+
+// RotateSigningKey godoc
+// @Summary Rotate the JWT signing key
+// @Description Admin-only: activate a new signing key immediately instead of waiting for the schedule. Tokens signed by the old key stay valid for the verification grace period unless revoke_current is set.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param rotate body validators.RotateSigningKeyInput false "Rotation options"
+// @Success 200 {object} dto.SigningKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/signing-keys/rotate [post]
+func RotateSigningKey(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	var data validators.RotateSigningKeyInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&data); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+		}
+	}
+
+	key, err := auth.RotateSigningKeyNow(data.RevokeCurrent)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot rotate signing key", err.Error()})
+	}
+
+	if err := recordAudit(db.DB, principal, "signing_key.rotate", "signing_key", key.ID, fiber.Map{"kid": key.KeyID, "revoke_current": data.RevokeCurrent}); err != nil {
+		log.Printf("Failed to record signing key rotation: %v\n", err)
+	}
+
+	return c.JSON(dto.FromSigningKey(key))
+}
@@ -0,0 +1,451 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var errLastActiveAdmin = errors.New("cannot deactivate the last active admin")
+
+// staffCodeColumn adalah kolom ID login (operator_id / admin_id) untuk role staff
+func staffCodeColumn(role string) string {
+	if role == auth.RoleAdmin {
+		return "admin_id"
+	}
+	return "operator_id"
+}
+
+// staffConflict memeriksa apakah ID login atau email sudah dipakai akun staff lain
+func staffConflict(role string, code string, email string, excludeID uint) (bool, error) {
+	query := db.DB.Model(staffModel(role)).Where("id <> ?", excludeID)
+	if code != "" {
+		query = query.Where(staffCodeColumn(role)+" = ? OR email = ?", code, email)
+	} else {
+		query = query.Where("email = ?", email)
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// createStaff menyimpan akun baru yang password awalnya sudah di-hash oleh pemanggil.
+// accountID dipanggil setelah Create untuk mencatat ID akun di audit log.
+func createStaff(c *fiber.Ctx, role string, code string, email string, account interface{}, accountID func() uint, details fiber.Map) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	conflict, err := staffConflict(role, code, email, 0)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot create account", err.Error()})
+	}
+	if conflict {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The " + role + " ID or email is already in use"})
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		// Role sudah ada; hanya baris tabel join yang dibuat
+		if err := tx.Omit("Roles.*").Create(account).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, role+".create", role, accountID(), details)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot create account", err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(dto.FromStaff(account))
+}
+
+// updateStaff mengubah data kontak akun staff dan, jika diminta, mereset passwordnya
+func updateStaff(c *fiber.Ctx, role string) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid account ID"})
+	}
+
+	var data validators.UpdateStaffInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	account := staffModel(role)
+	if err := db.DB.First(account, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{role + " not found", "No " + role + " with the given ID"})
+	}
+
+	conflict, err := staffConflict(role, "", data.Email, uint(id))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+	}
+	if conflict {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Email already in use", "Another " + role + " already uses this email"})
+	}
+
+	changes := map[string]interface{}{
+		"name":  data.Name,
+		"email": data.Email,
+		"phone": data.Phone,
+	}
+	details := fiber.Map{"name": data.Name, "email": data.Email, "phone": data.Phone}
+
+	if data.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(data.Password), 14)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot hash password", err.Error()})
+		}
+		changes["password"] = hash
+		changes["must_change_password"] = true
+		details["password_reset"] = true
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(changes).Error; err != nil {
+			return err
+		}
+
+		// Reset password memaksa login ulang di semua perangkat
+		if data.Password != "" {
+			if err := auth.RevokeAllSessionsTx(tx, role, uint(id)); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, principal, role+".update", role, uint(id), details)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+	}
+
+	db.DB.First(account, id)
+	return c.JSON(dto.FromStaff(account))
+}
+
+// setStaffActive menonaktifkan atau mengaktifkan kembali akun staff
+func setStaffActive(c *fiber.Ctx, role string, active bool) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid account ID"})
+	}
+
+	if !active && role == auth.RoleAdmin && uint(id) == principal.SubjectID {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "You cannot deactivate your own account"})
+	}
+
+	account := staffModel(role)
+	if err := db.DB.First(account, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{role + " not found", "No " + role + " with the given ID"})
+	}
+
+	action := role + ".reactivate"
+	if !active {
+		action = role + ".deactivate"
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("active", active).Error; err != nil {
+			return err
+		}
+
+		// Selalu sisakan minimal satu admin aktif
+		if !active && role == auth.RoleAdmin {
+			var remaining int64
+			if err := tx.Model(&models.Admin{}).Where("active = ?", true).Count(&remaining).Error; err != nil {
+				return err
+			}
+			if remaining == 0 {
+				return errLastActiveAdmin
+			}
+		}
+
+		if !active {
+			if err := auth.RevokeAllSessionsTx(tx, role, uint(id)); err != nil {
+				return err
+			}
+		}
+		return recordAudit(tx, principal, action, role, uint(id), fiber.Map{"active": active})
+	})
+	if errors.Is(err, errLastActiveAdmin) {
+		return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"invalid input", err.Error()})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+	}
+
+	db.DB.First(account, id)
+	return c.JSON(dto.FromStaff(account))
+}
+
+// CreateOperator godoc
+// @Summary Create an operator
+// @Description Admin-only: create an operator account with an initial password that must be changed on first login
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param operator body validators.CreateOperatorInput true "Operator details"
+// @Success 201 {object} dto.OperatorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators [post]
+func CreateOperator(c *fiber.Ctx) error {
+	var data validators.CreateOperatorInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	roleNames := data.Roles
+	if len(roleNames) == 0 {
+		roleNames = []string{models.RoleNameOperator}
+	}
+	roles, err := findRoles(db.DB, roleNames)
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot create account")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(data.Password), 14)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot hash password", err.Error()})
+	}
+
+	// Password awal wajib diganti saat login pertama
+	operator := &models.Operator{
+		OperatorID:         data.OperatorID,
+		Name:               data.Name,
+		Email:              data.Email,
+		Phone:              data.Phone,
+		Password:           hash,
+		MustChangePassword: true,
+		Active:             true,
+		Roles:              roles,
+	}
+	return createStaff(c, auth.RoleOperator, data.OperatorID, data.Email, operator, func() uint { return operator.ID }, fiber.Map{
+		"operator_id": data.OperatorID,
+		"name":        data.Name,
+		"email":       data.Email,
+		"phone":       data.Phone,
+		"roles":       roleNames,
+	})
+}
+
+// ListOperators godoc
+// @Summary List operators
+// @Description Admin-only: list operator accounts, optionally filtered by active status
+// @Tags admin
+// @Produce json
+// @Param active query bool false "Filter by active status"
+// @Success 200 {array} dto.OperatorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators [get]
+func ListOperators(c *fiber.Ctx) error {
+	query := db.DB.Preload("Roles").Order("id")
+	if active := c.Query("active"); active != "" {
+		query = query.Where("active = ?", c.QueryBool("active"))
+	}
+
+	var operators []models.Operator
+	if err := query.Find(&operators).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve operators", err.Error()})
+	}
+	return c.JSON(dto.FromOperators(operators))
+}
+
+// UpdateOperator godoc
+// @Summary Update an operator
+// @Description Admin-only: update an operator's contact details and optionally reset the password
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Operator ID"
+// @Param operator body validators.UpdateStaffInput true "Operator details"
+// @Success 200 {object} dto.OperatorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators/{id} [put]
+func UpdateOperator(c *fiber.Ctx) error {
+	return updateStaff(c, auth.RoleOperator)
+}
+
+// DeactivateOperator godoc
+// @Summary Deactivate an operator
+// @Description Admin-only: block an operator from logging in and revoke all of their sessions
+// @Tags admin
+// @Produce json
+// @Param id path int true "Operator ID"
+// @Success 200 {object} dto.OperatorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators/{id}/deactivate [put]
+func DeactivateOperator(c *fiber.Ctx) error {
+	return setStaffActive(c, auth.RoleOperator, false)
+}
+
+// ReactivateOperator godoc
+// @Summary Reactivate an operator
+// @Description Admin-only: allow a deactivated operator to log in again
+// @Tags admin
+// @Produce json
+// @Param id path int true "Operator ID"
+// @Success 200 {object} dto.OperatorResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/operators/{id}/reactivate [put]
+func ReactivateOperator(c *fiber.Ctx) error {
+	return setStaffActive(c, auth.RoleOperator, true)
+}
+
+// CreateAdmin godoc
+// @Summary Create an admin
+// @Description Admin-only: create an admin account with an initial password that must be changed on first login
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param admin body validators.CreateAdminInput true "Admin details"
+// @Success 201 {object} dto.AdminResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins [post]
+func CreateAdmin(c *fiber.Ctx) error {
+	var data validators.CreateAdminInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	roleNames := data.Roles
+	if len(roleNames) == 0 {
+		roleNames = []string{models.RoleNameAdmin}
+	}
+	roles, err := findRoles(db.DB, roleNames)
+	if err != nil {
+		return roleErrorResponse(c, err, "Cannot create account")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(data.Password), 14)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot hash password", err.Error()})
+	}
+
+	// Password awal wajib diganti saat login pertama
+	admin := &models.Admin{
+		AdminID:            data.AdminID,
+		Name:               data.Name,
+		Email:              data.Email,
+		Phone:              data.Phone,
+		Password:           hash,
+		MustChangePassword: true,
+		Active:             true,
+		Roles:              roles,
+	}
+	return createStaff(c, auth.RoleAdmin, data.AdminID, data.Email, admin, func() uint { return admin.ID }, fiber.Map{
+		"admin_id": data.AdminID,
+		"name":     data.Name,
+		"email":    data.Email,
+		"phone":    data.Phone,
+		"roles":    roleNames,
+	})
+}
+
+// ListAdmins godoc
+// @Summary List admins
+// @Description Admin-only: list admin accounts, optionally filtered by active status
+// @Tags admin
+// @Produce json
+// @Param active query bool false "Filter by active status"
+// @Success 200 {array} dto.AdminResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins [get]
+func ListAdmins(c *fiber.Ctx) error {
+	query := db.DB.Preload("Roles").Order("id")
+	if active := c.Query("active"); active != "" {
+		query = query.Where("active = ?", c.QueryBool("active"))
+	}
+
+	var admins []models.Admin
+	if err := query.Find(&admins).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve admins", err.Error()})
+	}
+	return c.JSON(dto.FromAdmins(admins))
+}
+
+// UpdateAdmin godoc
+// @Summary Update an admin
+// @Description Admin-only: update an admin's contact details and optionally reset the password
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Param admin body validators.UpdateStaffInput true "Admin details"
+// @Success 200 {object} dto.AdminResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins/{id} [put]
+func UpdateAdmin(c *fiber.Ctx) error {
+	return updateStaff(c, auth.RoleAdmin)
+}
+
+// DeactivateAdmin godoc
+// @Summary Deactivate an admin
+// @Description Admin-only: block another admin from logging in and revoke all of their sessions
+// @Tags admin
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Success 200 {object} dto.AdminResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins/{id}/deactivate [put]
+func DeactivateAdmin(c *fiber.Ctx) error {
+	return setStaffActive(c, auth.RoleAdmin, false)
+}
+
+// ReactivateAdmin godoc
+// @Summary Reactivate an admin
+// @Description Admin-only: allow a deactivated admin to log in again
+// @Tags admin
+// @Produce json
+// @Param id path int true "Admin ID"
+// @Success 200 {object} dto.AdminResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/admins/{id}/reactivate [put]
+func ReactivateAdmin(c *fiber.Ctx) error {
+	return setStaffActive(c, auth.RoleAdmin, true)
+}
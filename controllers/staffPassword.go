@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// verifyPassword membandingkan password dengan hash bcrypt. Jika hash kosong
+// (akun tidak ditemukan atau belum punya password), perbandingan tetap dilakukan
+// terhadap hash dummy agar waktu respons tidak membocorkan keberadaan akun.
+func verifyPassword(hash []byte, password string) bool {
+	if len(hash) == 0 {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), 14)
+		})
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
+
+// staffCredentials berisi kolom password dari tabel operators atau admins
+type staffCredentials struct {
+	Password           []byte
+	MustChangePassword bool
+}
+
+// staffModel mengembalikan model GORM untuk role staff
+func staffModel(role string) interface{} {
+	if role == auth.RoleAdmin {
+		return &models.Admin{}
+	}
+	return &models.Operator{}
+}
+
+// staffSubject mengambil ID staff dari principal yang di-set oleh auth.RequireRole
+func staffSubject(c *fiber.Ctx) (string, bool) {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return "", false
+	}
+	return principal.Subject(), true
+}
+
+func findStaffCredentials(role string, id string) (*staffCredentials, error) {
+	var creds staffCredentials
+	err := db.DB.Model(staffModel(role)).
+		Select("password", "must_change_password").
+		Where("id = ?", id).
+		Take(&creds).Error
+	return &creds, err
+}
+
+// PasswordChangeGuard menolak akses staff yang masih wajib mengganti password
+// awal. Route untuk mengganti password dan logout harus didaftarkan sebelum
+// middleware ini.
+func PasswordChangeGuard(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// API key tidak punya password; scope-nya dicek oleh RequirePermission
+		if _, ok := auth.APIKeyPrincipalFrom(c); ok {
+			return c.Next()
+		}
+
+		id, ok := staffSubject(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+				Message: "unauthenticated",
+				Error:   "Invalid token claims",
+			})
+		}
+
+		creds, err := findStaffCredentials(role, id)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+				Message: "unauthenticated",
+				Error:   "Account not found",
+			})
+		}
+
+		if creds.MustChangePassword {
+			return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+				Message: "Password change required",
+				Error:   "You must change your password before continuing",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+func changeStaffPassword(c *fiber.Ctx, role string) error {
+	id, ok := staffSubject(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid token claims"})
+	}
+
+	var data validators.UpdatePasswordInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	creds, err := findStaffCredentials(role, id)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Account not found"})
+	}
+
+	// Verify old password
+	if !verifyPassword(creds.Password, data.OldPassword) {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"incorrect old password", "Authentication failed"})
+	}
+
+	if data.OldPassword == data.NewPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", "New password must differ from the old password"})
+	}
+
+	// Generate new hashed password
+	newPassword, err := bcrypt.GenerateFromPassword([]byte(data.NewPassword), 14)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot hash new password", err.Error()})
+	}
+
+	if err := db.DB.Model(staffModel(role)).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":             newPassword,
+		"must_change_password": false,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update password", err.Error()})
+	}
+
+	return c.JSON(SuccessResponse{Message: "password updated successfully"})
+}
+
+// ChangeOperatorPassword godoc
+// @Summary Change operator password
+// @Description Change the logged-in operator's password. Required before any other operator endpoint when the account still has its initial password.
+// @Tags operator
+// @Accept json
+// @Produce json
+// @Param update body validators.UpdatePasswordInput true "Old and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/password [put]
+func ChangeOperatorPassword(c *fiber.Ctx) error {
+	return changeStaffPassword(c, auth.RoleOperator)
+}
+
+// ChangeAdminPassword godoc
+// @Summary Change admin password
+// @Description Change the logged-in admin's password. Required before any other admin endpoint when the account still has its initial password.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param update body validators.UpdatePasswordInput true "Old and new password"
+// @Success 200 {object} SuccessResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/password [put]
+func ChangeAdminPassword(c *fiber.Ctx) error {
+	return changeStaffPassword(c, auth.RoleAdmin)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+)
+
+// TokenResponse adalah bentuk token yang sama untuk login dan refresh semua role.
+// RefreshToken hanya diisi pada mode token (header X-Auth-Mode: token); klien
+// browser menerimanya lewat cookie HttpOnly dan sebaliknya menerima CSRFToken
+// yang harus dikirim di header X-CSRF-Token pada setiap request non-GET.
+type TokenResponse struct {
+	AccessToken      string     `json:"access_token"`
+	TokenType        string     `json:"token_type"`
+	ExpiresIn        int64      `json:"expires_in"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	RefreshToken     string     `json:"refresh_token,omitempty"`
+	RefreshExpiresAt *time.Time `json:"refresh_expires_at,omitempty"`
+	CSRFToken        string     `json:"csrf_token,omitempty"`
+}
+
+// RefreshTokenResponse dikembalikan setelah refresh token berhasil dirotasi.
+// Token dan ExpiresAt dipertahankan untuk frontend lama; klien baru memakai Tokens.
+type RefreshTokenResponse struct {
+	Message   string        `json:"message"`
+	Token     string        `json:"token"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Tokens    TokenResponse `json:"tokens"`
+}
+
+func newTokenResponse(c *fiber.Ctx, pair *auth.TokenPair) TokenResponse {
+	response := TokenResponse{
+		AccessToken: pair.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(pair.AccessExpiresAt).Seconds()),
+		ExpiresAt:   pair.AccessExpiresAt,
+	}
+	if auth.TokenMode(c) {
+		response.RefreshToken = pair.RefreshToken
+		response.RefreshExpiresAt = &pair.RefreshExpiresAt
+	} else {
+		response.CSRFToken = pair.CSRFToken
+	}
+	return response
+}
+
+func refreshToken(c *fiber.Ctx, role string) error {
+	pair, err := auth.RotateSession(c, role)
+	switch {
+	case errors.Is(err, auth.ErrMissingRefreshToken),
+		errors.Is(err, auth.ErrInvalidRefreshToken),
+		errors.Is(err, auth.ErrRefreshTokenReused),
+		errors.Is(err, auth.ErrSessionRevoked),
+		errors.Is(err, auth.ErrAccountInactive):
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{
+			Message: "unauthenticated",
+			Error:   err.Error(),
+		})
+	case errors.Is(err, auth.ErrInvalidCSRFToken):
+		return c.Status(fiber.StatusForbidden).JSON(ErrorResponse{
+			Message: "forbidden",
+			Error:   err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+			Message: "Could not refresh token",
+			Error:   err.Error(),
+		})
+	}
+
+	return c.JSON(RefreshTokenResponse{
+		Message:   "Token refreshed",
+		Token:     pair.AccessToken,
+		ExpiresAt: pair.AccessExpiresAt,
+		Tokens:    newTokenResponse(c, pair),
+	})
+}
+
+// RefreshUserToken godoc
+// @Summary Refresh the user access token
+// @Description Rotate the user's refresh token and issue a new access token. Browsers send the refresh cookie together with the session CSRF token in X-CSRF-Token; with X-Auth-Mode: token the refresh token is read from the JSON body (refresh_token) and no cookies are set.
+// @Tags auth
+// @Produce json
+// @Param X-Auth-Mode header string false "Set to token for the JSON-only mode"
+// @Param X-CSRF-Token header string false "CSRF token of the session, required when the refresh token comes from the cookie"
+// @Success 200 {object} RefreshTokenResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/token/refresh [post]
+func RefreshUserToken(c *fiber.Ctx) error {
+	return refreshToken(c, auth.RoleUser)
+}
+
+// RefreshOperatorToken godoc
+// @Summary Refresh the operator access token
+// @Description Rotate the operator's refresh token and issue a new access token. Browsers send the refresh cookie together with the session CSRF token in X-CSRF-Token; with X-Auth-Mode: token the refresh token is read from the JSON body (refresh_token) and no cookies are set.
+// @Tags auth
+// @Produce json
+// @Param X-Auth-Mode header string false "Set to token for the JSON-only mode"
+// @Param X-CSRF-Token header string false "CSRF token of the session, required when the refresh token comes from the cookie"
+// @Success 200 {object} RefreshTokenResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /operator/token/refresh [post]
+func RefreshOperatorToken(c *fiber.Ctx) error {
+	return refreshToken(c, auth.RoleOperator)
+}
+
+// RefreshAdminToken godoc
+// @Summary Refresh the admin access token
+// @Description Rotate the admin's refresh token and issue a new access token. Browsers send the refresh cookie together with the session CSRF token in X-CSRF-Token; with X-Auth-Mode: token the refresh token is read from the JSON body (refresh_token) and no cookies are set.
+// @Tags auth
+// @Produce json
+// @Param X-Auth-Mode header string false "Set to token for the JSON-only mode"
+// @Param X-CSRF-Token header string false "CSRF token of the session, required when the refresh token comes from the cookie"
+// @Success 200 {object} RefreshTokenResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/token/refresh [post]
+func RefreshAdminToken(c *fiber.Ctx) error {
+	return refreshToken(c, auth.RoleAdmin)
+}
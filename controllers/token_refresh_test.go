@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// TestRefreshTokenRequiresCSRF memastikan refresh lewat cookie ditolak tanpa
+// token CSRF sesi tanpa mencabut atau menghapus cookie sesi, sedangkan mode
+// token (refresh token di body) tidak memerlukannya.
+func TestRefreshTokenRequiresCSRF(t *testing.T) {
+	conn := openTestDB(t)
+
+	now := time.Now()
+	suffix := strconv.FormatInt(now.UnixNano(), 36)
+	user := models.User{Email: "refresh-" + suffix + "@example.test", Username: "refresh", Password: []byte("x"), EmailVerifiedAt: &now}
+	if err := conn.Create(&user).Error; err != nil {
+		t.Fatalf("cannot seed user: %v", err)
+	}
+
+	const csrfToken = "csrf-token"
+	session := models.Session{Role: auth.RoleUser, SubjectID: uint(user.ID), LastSeenAt: now, CSRFTokenHash: auth.HashToken(csrfToken)}
+	if err := conn.Create(&session).Error; err != nil {
+		t.Fatalf("cannot seed session: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Where("session_id = ?", session.ID).Delete(&models.RefreshToken{})
+		conn.Delete(&session)
+		conn.Unscoped().Delete(&user)
+	})
+
+	app := fiber.New()
+	app.Post("/api/token/refresh", RefreshUserToken)
+
+	cookieRefresh := func(token string, csrf string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", nil)
+		req.AddCookie(&http.Cookie{Name: "jwt_refresh", Value: token})
+		if csrf != "" {
+			req.Header.Set(auth.CSRFHeader, csrf)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("refresh request failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("cookie without CSRF token", func(t *testing.T) {
+		token, record := seedRefreshToken(t, conn, session)
+		for _, csrf := range []string{"", "wrong-token"} {
+			resp := cookieRefresh(token, csrf)
+			if resp.StatusCode != fiber.StatusForbidden {
+				t.Errorf("csrf %q: expected 403, got %d", csrf, resp.StatusCode)
+			}
+			if cookies := resp.Header.Values("Set-Cookie"); len(cookies) != 0 {
+				t.Errorf("csrf %q: session cookies must be left alone, got %v", csrf, cookies)
+			}
+		}
+
+		var reloaded models.RefreshToken
+		conn.First(&reloaded, record.ID)
+		if reloaded.RevokedAt != nil {
+			t.Error("refresh token was revoked by a request without CSRF token")
+		}
+	})
+
+	t.Run("cookie with CSRF token", func(t *testing.T) {
+		token, _ := seedRefreshToken(t, conn, session)
+		resp := cookieRefresh(token, csrfToken)
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+
+		var refreshCookie string
+		for _, cookie := range resp.Header.Values("Set-Cookie") {
+			if strings.HasPrefix(cookie, "jwt_refresh=") {
+				refreshCookie = cookie
+			}
+		}
+		if !strings.Contains(refreshCookie, "SameSite=Strict") {
+			t.Errorf("refresh cookie should be SameSite=Strict, got %q", refreshCookie)
+		}
+	})
+
+	t.Run("token mode", func(t *testing.T) {
+		token, _ := seedRefreshToken(t, conn, session)
+		req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", strings.NewReader(`{"refresh_token":"`+token+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(auth.TokenModeHeader, "token")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("refresh request failed: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Errorf("expected 200, got %d", resp.StatusCode)
+		}
+	})
+}
+
+// seedRefreshToken menyimpan refresh token aktif untuk sesi dan mengembalikan nilai mentahnya
+func seedRefreshToken(t *testing.T, conn *gorm.DB, session models.Session) (string, models.RefreshToken) {
+	t.Helper()
+
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		t.Fatalf("cannot create refresh token: %v", err)
+	}
+	record := models.RefreshToken{
+		Role:      session.Role,
+		SubjectID: session.SubjectID,
+		SessionID: session.ID,
+		TokenHash: auth.HashToken(token),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := conn.Create(&record).Error; err != nil {
+		t.Fatalf("cannot seed refresh token: %v", err)
+	}
+	return token, record
+}
@@ -0,0 +1,364 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/db"
+	"github.com/raihan1405/go-restapi/dto"
+	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/validators"
+	"gorm.io/gorm"
+)
+
+var errInvalidUserFilter = errors.New("invalid filter")
+
+// UserListResponse adalah satu halaman hasil pencarian customer
+type UserListResponse struct {
+	Users   []dto.AdminUserResponse `json:"users"`
+	Total   int64                   `json:"total"`
+	Page    int                     `json:"page"`
+	PerPage int                     `json:"per_page"`
+}
+
+// customerStatsRow adalah jumlah pesanan dan total belanja satu customer
+type customerStatsRow struct {
+	UserID     string
+	OrderCount int64
+	TotalSpent float64
+}
+
+// userListRow adalah user beserta statistik pesanan hasil join customerStats
+type userListRow struct {
+	models.User
+	OrderCount int64
+	TotalSpent float64
+}
+
+// userSortColumns adalah kolom yang boleh dipakai untuk mengurutkan daftar customer
+var userSortColumns = map[string]string{
+	"id":          "users.id",
+	"created_at":  "users.created_at",
+	"email":       "users.email",
+	"username":    "users.username",
+	"order_count": "order_count",
+	"total_spent": "total_spent",
+}
+
+// customerStats menghitung jumlah pesanan dan total belanja per customer.
+// Invoice yang ditolak atau dibatalkan tidak dihitung.
+func customerStats(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&models.Invoice{}).
+		Select("user_id, COUNT(*) AS order_count, COALESCE(SUM(total_price), 0) AS total_spent").
+		Where("status NOT IN ?", []string{models.InvoiceStatusRejected, models.InvoiceStatusCancelled}).
+		Group("user_id")
+}
+
+// parseUserDate menerima tanggal YYYY-MM-DD atau RFC3339
+func parseUserDate(value string) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errInvalidUserFilter
+	}
+	return t, false, nil
+}
+
+// filterUsers menerapkan pencarian dan filter query string pada daftar customer
+func filterUsers(c *fiber.Ctx, query *gorm.DB) (*gorm.DB, error) {
+	if q := c.Query("q"); q != "" {
+		like := "%" + q + "%"
+		query = query.Where("users.email LIKE ? OR users.username LIKE ? OR users.phone_number LIKE ?", like, like, like)
+	}
+
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("users.suspended_at IS NULL")
+	case "suspended":
+		query = query.Where("users.suspended_at IS NOT NULL")
+	default:
+		return nil, errInvalidUserFilter
+	}
+
+	from, _, err := parseUserDate(c.Query("registered_from"))
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() {
+		query = query.Where("users.created_at >= ?", from)
+	}
+
+	// Tanggal tanpa jam pada registered_to berarti sampai akhir hari tersebut
+	to, dateOnly, err := parseUserDate(c.Query("registered_to"))
+	if err != nil {
+		return nil, err
+	}
+	if !to.IsZero() {
+		if dateOnly {
+			query = query.Where("users.created_at < ?", to.AddDate(0, 0, 1))
+		} else {
+			query = query.Where("users.created_at <= ?", to)
+		}
+	}
+
+	for _, filter := range []struct {
+		Param string
+		SQL   string
+	}{
+		{"min_orders", "COALESCE(stats.order_count, 0) >= ?"},
+		{"max_orders", "COALESCE(stats.order_count, 0) <= ?"},
+		{"min_spent", "COALESCE(stats.total_spent, 0) >= ?"},
+		{"max_spent", "COALESCE(stats.total_spent, 0) <= ?"},
+	} {
+		value := c.Query(filter.Param)
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errInvalidUserFilter
+		}
+		query = query.Where(filter.SQL, number)
+	}
+
+	return query, nil
+}
+
+// ListUsers godoc
+// @Summary List customers
+// @Description Admin-only: search customers by email, username or phone and filter by registration date, order count and total spent. Order count and total spent ignore rejected and cancelled invoices.
+// @Tags admin
+// @Produce json
+// @Param q query string false "Search text matched against email, username and phone number"
+// @Param status query string false "active or suspended"
+// @Param registered_from query string false "Registered on or after (YYYY-MM-DD or RFC3339)"
+// @Param registered_to query string false "Registered on or before (YYYY-MM-DD or RFC3339)"
+// @Param min_orders query int false "Minimum number of orders"
+// @Param max_orders query int false "Maximum number of orders"
+// @Param min_spent query number false "Minimum total spent"
+// @Param max_spent query number false "Maximum total spent"
+// @Param sort query string false "id, created_at, email, username, order_count or total_spent (default id)"
+// @Param order query string false "asc or desc (default desc)"
+// @Param page query int false "Page number (default 1)"
+// @Param per_page query int false "Results per page (default 20, max 100)"
+// @Success 200 {object} UserListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users [get]
+func ListUsers(c *fiber.Ctx) error {
+	query := db.DB.Model(&models.User{}).
+		Joins("LEFT JOIN (?) AS stats ON stats.user_id = users.id", customerStats(db.DB))
+
+	query, err := filterUsers(c, query)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid search filter"})
+	}
+
+	sortColumn, ok := userSortColumns[c.Query("sort", "id")]
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Unknown sort field"})
+	}
+	direction := "DESC"
+	switch c.Query("order", "desc") {
+	case "asc":
+		direction = "ASC"
+	case "desc":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "order must be asc or desc"})
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	perPage := c.QueryInt("per_page", 20)
+	if perPage <= 0 || perPage > 100 {
+		perPage = 20
+	}
+
+	// Query dasar dipakai dua kali: untuk total dan untuk halaman yang diminta
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve users", err.Error()})
+	}
+
+	var rows []userListRow
+	err = query.
+		Select("users.*, COALESCE(stats.order_count, 0) AS order_count, COALESCE(stats.total_spent, 0) AS total_spent").
+		Order(sortColumn + " " + direction).
+		Order("users.id " + direction).
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Scan(&rows).Error
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve users", err.Error()})
+	}
+
+	users := make([]dto.AdminUserResponse, len(rows))
+	for i := range rows {
+		users[i] = dto.FromAdminUser(&rows[i].User, rows[i].OrderCount, rows[i].TotalSpent)
+	}
+
+	return c.JSON(UserListResponse{
+		Users:   users,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+	})
+}
+
+// GetUserDetail godoc
+// @Summary Get a customer
+// @Description Admin-only: a customer's profile with order statistics, invoices, current cart and address book
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} dto.AdminUserDetailResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id} [get]
+func GetUserDetail(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid user ID"})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+	userID := strconv.Itoa(user.ID)
+
+	var stats customerStatsRow
+	if err := customerStats(db.DB).Where("user_id = ?", userID).Scan(&stats).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve user", err.Error()})
+	}
+
+	var invoices []models.Invoice
+	if err := db.DB.Preload("InvoiceItems.Product.Category").Where("user_id = ?", userID).Order("id desc").Find(&invoices).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve user", err.Error()})
+	}
+
+	var cartItems []models.CartItem
+	if err := db.DB.Preload("Product.Category").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve user", err.Error()})
+	}
+
+	var addresses []models.UserAddress
+	if err := db.DB.Where("user_id = ?", user.ID).Order("is_default desc, id").Find(&addresses).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"failed to retrieve user", err.Error()})
+	}
+
+	return c.JSON(dto.AdminUserDetailResponse{
+		AdminUserResponse: dto.FromAdminUser(&user, stats.OrderCount, stats.TotalSpent),
+		Invoices:          dto.FromInvoices(invoices, auth.RoleAdmin),
+		Cart:              dto.FromCartItems(cartItems),
+		Addresses:         dto.FromUserAddresses(addresses),
+	})
+}
+
+// UpdateUser godoc
+// @Summary Update a customer's contact details
+// @Description Admin-only: change a customer's username, email or phone number. Every changed field is written to the audit log with its old and new value. A new email must be verified again by the customer.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body validators.UpdateUserInput true "Contact details"
+// @Success 200 {object} dto.UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{id} [put]
+func UpdateUser(c *fiber.Ctx) error {
+	principal, ok := auth.PrincipalFrom(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(ErrorResponse{"unauthenticated", "Invalid or expired token"})
+	}
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"invalid input", "Invalid user ID"})
+	}
+
+	var data validators.UpdateUserInput
+	if err := c.BodyParser(&data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Cannot parse JSON", err.Error()})
+	}
+	if err := validators.Validate.Struct(data); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(ErrorResponse{"Validation error", err.Error()})
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(ErrorResponse{"user not found", "No user with the given ID"})
+	}
+
+	emailChanged := user.Email != data.Email
+	if emailChanged {
+		inUse, err := emailInUse(data.Email, user.ID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+		}
+		if inUse {
+			return c.Status(fiber.StatusConflict).JSON(ErrorResponse{"Account already exists", "The email is already in use"})
+		}
+	}
+
+	// Audit mencatat nilai lama dan baru hanya untuk field yang berubah
+	updates := map[string]interface{}{}
+	changes := fiber.Map{}
+	for _, field := range []struct {
+		Column string
+		Old    string
+		New    string
+	}{
+		{"username", user.Username, data.Username},
+		{"email", user.Email, data.Email},
+		{"phone_number", user.PhoneNumber, data.PhoneNumber},
+	} {
+		if field.Old != field.New {
+			updates[field.Column] = field.New
+			changes[field.Column] = fiber.Map{"from": field.Old, "to": field.New}
+		}
+	}
+	if len(updates) == 0 {
+		return c.JSON(dto.FromUser(&user))
+	}
+	if emailChanged {
+		updates["email_verified_at"] = nil
+	}
+
+	err = db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, principal, "user.update", auth.RoleUser, uint(user.ID), changes)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{"Cannot update account", err.Error()})
+	}
+
+	db.DB.First(&user, id)
+	if emailChanged {
+		if err := sendVerificationEmail(&user); err != nil {
+			log.Printf("Failed to send verification email to user %d: %v\n", user.ID, err)
+		}
+	}
+
+	return c.JSON(dto.FromUser(&user))
+}
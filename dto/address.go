@@ -0,0 +1,74 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// AddressResponse adalah isi alamat pengiriman
+type AddressResponse struct {
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	Street        string `json:"street"`
+	VillageID     string `json:"village_id"`
+	Village       string `json:"village"`
+	DistrictID    string `json:"district_id"`
+	District      string `json:"district"`
+	CityID        string `json:"city_id"`
+	City          string `json:"city"`
+	ProvinceID    string `json:"province_id"`
+	Province      string `json:"province"`
+	PostalCode    string `json:"postal_code"`
+	Notes         string `json:"notes"`
+}
+
+// UserAddressResponse adalah satu alamat di buku alamat customer
+type UserAddressResponse struct {
+	ID    uint   `json:"id"`
+	Label string `json:"label"`
+	AddressResponse
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FromAddress memetakan isi alamat
+func FromAddress(address *models.Address) AddressResponse {
+	return AddressResponse{
+		RecipientName: address.RecipientName,
+		Phone:         address.Phone,
+		Street:        address.Street,
+		VillageID:     address.VillageID,
+		Village:       address.Village,
+		DistrictID:    address.DistrictID,
+		District:      address.District,
+		CityID:        address.CityID,
+		City:          address.City,
+		ProvinceID:    address.ProvinceID,
+		Province:      address.Province,
+		PostalCode:    address.PostalCode,
+		Notes:         address.Notes,
+	}
+}
+
+// FromUserAddress memetakan satu alamat di buku alamat
+func FromUserAddress(address *models.UserAddress) UserAddressResponse {
+	return UserAddressResponse{
+		ID:              address.ID,
+		Label:           address.Label,
+		AddressResponse: FromAddress(&address.Address),
+		IsDefault:       address.IsDefault,
+		CreatedAt:       address.CreatedAt,
+		UpdatedAt:       address.UpdatedAt,
+	}
+}
+
+// FromUserAddresses memetakan seluruh buku alamat
+func FromUserAddresses(addresses []models.UserAddress) []UserAddressResponse {
+	responses := make([]UserAddressResponse, len(addresses))
+	for i := range addresses {
+		responses[i] = FromUserAddress(&addresses[i])
+	}
+	return responses
+}
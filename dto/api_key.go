@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// APIKeyResponse adalah data API key tanpa hash key-nya
+type APIKeyResponse struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	Prefix      string               `json:"prefix"`
+	Permissions []PermissionResponse `json:"permissions"`
+	RateLimit   int                  `json:"rate_limit"`
+	ExpiresAt   *time.Time           `json:"expires_at"`
+	LastUsedAt  *time.Time           `json:"last_used_at"`
+	LastUsedIP  string               `json:"last_used_ip"`
+	RevokedAt   *time.Time           `json:"revoked_at"`
+	CreatedByID uint                 `json:"created_by_id"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// FromAPIKey memetakan API key beserta permission yang sudah di-preload
+func FromAPIKey(key *models.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Permissions: FromPermissions(key.Permissions),
+		RateLimit:   key.RateLimit,
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+		LastUsedIP:  key.LastUsedIP,
+		RevokedAt:   key.RevokedAt,
+		CreatedByID: key.CreatedByID,
+		CreatedAt:   key.CreatedAt,
+	}
+}
+
+// FromAPIKeys memetakan daftar API key
+func FromAPIKeys(keys []models.APIKey) []APIKeyResponse {
+	responses := make([]APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = FromAPIKey(&keys[i])
+	}
+	return responses
+}
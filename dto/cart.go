@@ -0,0 +1,43 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// CartItemResponse adalah satu item di keranjang customer
+type CartItemResponse struct {
+	ID         int             `json:"id"`
+	ProductID  int             `json:"productId"`
+	UserID     string          `json:"userId"`
+	Quantity   int             `json:"quantity"`
+	Product    ProductResponse `json:"Product"`
+	TotalPrice float64         `json:"total_price"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// FromCartItem memetakan item keranjang; total dihitung dari harga produk saat ini
+func FromCartItem(item *models.CartItem) CartItemResponse {
+	return CartItemResponse{
+		ID:         item.ID,
+		ProductID:  item.ProductID,
+		UserID:     item.UserID,
+		Quantity:   item.Quantity,
+		Product:    FromProduct(&item.Product, auth.RoleUser),
+		TotalPrice: float64(item.Quantity * item.Product.Price),
+		CreatedAt:  item.CreatedAt,
+		UpdatedAt:  item.UpdatedAt,
+	}
+}
+
+// FromCartItems memetakan seluruh isi keranjang
+func FromCartItems(items []models.CartItem) []CartItemResponse {
+	responses := make([]CartItemResponse, len(items))
+	for i := range items {
+		responses[i] = FromCartItem(&items[i])
+	}
+	return responses
+}
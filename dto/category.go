@@ -0,0 +1,80 @@
+package dto
+
+import (
+	"sort"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// CategorySummary adalah kategori yang ditempelkan pada data produk
+type CategorySummary struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// CategoryResponse adalah satu node pohon kategori
+type CategoryResponse struct {
+	ID        uint               `json:"id"`
+	ParentID  *uint              `json:"parent_id"`
+	Name      string             `json:"name"`
+	Slug      string             `json:"slug"`
+	SortOrder int                `json:"sort_order"`
+	Active    bool               `json:"active"`
+	Children  []CategoryResponse `json:"children"`
+}
+
+// FromCategorySummary mengembalikan nil untuk produk tanpa kategori
+func FromCategorySummary(category *models.Category) *CategorySummary {
+	if category == nil {
+		return nil
+	}
+	return &CategorySummary{ID: category.ID, Name: category.Name, Slug: category.Slug}
+}
+
+// FromCategory memetakan satu kategori tanpa turunannya
+func FromCategory(category *models.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:        category.ID,
+		ParentID:  category.ParentID,
+		Name:      category.Name,
+		Slug:      category.Slug,
+		SortOrder: category.SortOrder,
+		Active:    category.Active,
+		Children:  []CategoryResponse{},
+	}
+}
+
+// CategoryTree menyusun daftar kategori datar menjadi pohon, diurutkan menurut
+// sort_order lalu nama. Kategori yang induknya tidak ada di daftar (misalnya
+// induk tidak aktif yang sudah disaring) tidak ikut ditampilkan.
+func CategoryTree(categories []models.Category) []CategoryResponse {
+	sorted := append([]models.Category(nil), categories...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].SortOrder != sorted[j].SortOrder {
+			return sorted[i].SortOrder < sorted[j].SortOrder
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	children := map[uint][]models.Category{}
+	var roots []models.Category
+	for _, category := range sorted {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	var build func(nodes []models.Category) []CategoryResponse
+	build = func(nodes []models.Category) []CategoryResponse {
+		responses := make([]CategoryResponse, len(nodes))
+		for i, node := range nodes {
+			responses[i] = FromCategory(&node)
+			responses[i].Children = build(children[node.ID])
+		}
+		return responses
+	}
+	return build(roots)
+}
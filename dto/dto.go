@@ -0,0 +1,17 @@
+// Package dto berisi tipe response JSON yang dikirim handler ke client.
+// Model akun (customer, operator, admin), API key, kunci penandatangan JWT,
+// identity OIDC, role, produk, kategori, keranjang dan invoice tidak pernah
+// dikirim langsung: response-nya dibentuk lewat fungsi mapping di sini sehingga
+// field rahasia (hash password, secret MFA, hash API key, private key) tidak
+// ikut terserialisasi dan field internal hanya terlihat oleh staff. Data tanpa
+// field rahasia seperti audit log, wilayah dan laporan stok masih dikirim
+// sebagai model.
+package dto
+
+import "github.com/raihan1405/go-restapi/auth"
+
+// staffView menandakan viewer adalah operator, admin atau integrasi API key yang
+// boleh melihat field internal
+func staffView(role string) bool {
+	return role == auth.RoleOperator || role == auth.RoleAdmin || role == auth.RoleService
+}
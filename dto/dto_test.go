@@ -0,0 +1,193 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raihan1405/go-restapi/auth"
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// Nilai rahasia dibuat unik agar mudah dicari di JSON hasil serialisasi
+const (
+	secretPassword   = "bcrypt-hash-should-not-leak"
+	secretMFA        = "MFASECRETSHOULDNOTLEAK"
+	secretKeyHash    = "api-key-hash-should-not-leak"
+	secretPrivateKey = "private-key-should-not-leak"
+	productOperator  = "OP-SECRET-42"
+)
+
+// secretFields adalah nama field yang tidak boleh ada di response mana pun
+var secretFields = []string{`"password"`, `"mfa_secret"`, `"key_hash"`, `"private_key"`}
+
+func marshal(t *testing.T, v interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal %T: %v", v, err)
+	}
+	return string(body)
+}
+
+func assertNoSecrets(t *testing.T, name string, body string) {
+	t.Helper()
+	lower := strings.ToLower(body)
+	for _, field := range secretFields {
+		if strings.Contains(lower, field) {
+			t.Errorf("%s: response contains %s: %s", name, field, body)
+		}
+	}
+	for _, value := range []string{secretPassword, secretMFA, secretKeyHash, secretPrivateKey} {
+		if strings.Contains(body, value) {
+			t.Errorf("%s: response leaks secret value %q: %s", name, value, body)
+		}
+	}
+}
+
+func testUser() models.User {
+	now := time.Now()
+	return models.User{
+		ID:          7,
+		Email:       "budi@example.com",
+		PhoneNumber: "08123456789",
+		Username:    "budi",
+		Password:    []byte(secretPassword),
+		CreatedAt:   &now,
+	}
+}
+
+func testProduct() models.Product {
+	return models.Product{
+		ID:          3,
+		ProductName: "Sepatu Lari",
+		BrandName:   "Nike",
+		Price:       500000,
+		Status:      true,
+		Quantity:    10,
+		OperatorID:  productOperator,
+		Category:    &models.Category{ID: 1, Name: "Sepatu", Slug: "sepatu"},
+	}
+}
+
+func testRoles() []models.Role {
+	return []models.Role{{
+		ID:          1,
+		Name:        models.RoleNameOperator,
+		BuiltIn:     true,
+		Permissions: []models.Permission{{ID: 1, Name: "products.write"}},
+	}}
+}
+
+func TestResponsesDoNotLeakSecrets(t *testing.T) {
+	user := testUser()
+	product := testProduct()
+	addressID := uint(2)
+	invoice := models.Invoice{
+		ID:                11,
+		UserID:            "7",
+		User:              user,
+		TotalPrice:        1000000,
+		Status:            models.InvoiceStatusPending,
+		ShippingAddressID: &addressID,
+		InvoiceItems:      []models.InvoiceItem{{ID: 1, InvoiceID: 11, ProductID: product.ID, Quantity: 2, Price: 500000, Total: 1000000, Product: product}},
+	}
+	cartItem := models.CartItem{ID: 5, ProductID: product.ID, UserID: "7", Quantity: 1, Product: product}
+
+	operator := models.Operator{
+		ID:          4,
+		OperatorID:  "OP-1",
+		Name:        "Sari",
+		Email:       "sari@example.com",
+		Password:    []byte(secretPassword),
+		Active:      true,
+		Roles:       testRoles(),
+		MFASecret:   secretMFA,
+		MFAEnabled:  true,
+		MFALastStep: 123,
+	}
+	admin := models.Admin{
+		ID:        1,
+		AdminID:   "AD-1",
+		Name:      "Andi",
+		Email:     "andi@example.com",
+		Password:  []byte(secretPassword),
+		Active:    true,
+		Roles:     testRoles(),
+		MFASecret: secretMFA,
+	}
+	apiKey := models.APIKey{
+		ID:          9,
+		Name:        "warehouse sync",
+		Prefix:      "sk_abcd",
+		KeyHash:     secretKeyHash,
+		Permissions: []models.Permission{{ID: 2, Name: "stock.write"}},
+		RateLimit:   60,
+	}
+	identity := models.UserIdentity{ID: 1, UserID: user.ID, Issuer: "https://accounts.example.com", Subject: "abc", Email: user.Email}
+	signingKey := models.SigningKey{ID: 1, KeyID: "kid-1", Algorithm: "RS256", PrivateKey: []byte(secretPrivateKey), PublicKey: []byte("public")}
+
+	for _, tc := range []struct {
+		name     string
+		response interface{}
+	}{
+		{"FromUser", FromUser(&user)},
+		{"FromCustomer", FromCustomer(&user)},
+		{"FromProduct", FromProduct(&product, auth.RoleAdmin)},
+		{"FromInvoice", FromInvoice(&invoice, auth.RoleAdmin)},
+		{"FromCartItem", FromCartItem(&cartItem)},
+		{"FromOperator", FromOperator(&operator)},
+		{"FromAdmin", FromAdmin(&admin)},
+		{"FromStaff", FromStaff(&operator)},
+		{"FromAPIKey", FromAPIKey(&apiKey)},
+		{"FromIdentities", FromIdentities([]models.UserIdentity{identity})},
+		{"FromSigningKey", FromSigningKey(&signingKey)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assertNoSecrets(t, tc.name, marshal(t, tc.response))
+		})
+	}
+}
+
+func TestOperatorIDHiddenFromCustomers(t *testing.T) {
+	product := testProduct()
+	invoice := models.Invoice{
+		ID:           11,
+		UserID:       "7",
+		InvoiceItems: []models.InvoiceItem{{ID: 1, InvoiceID: 11, ProductID: product.ID, Quantity: 1, Product: product}},
+	}
+	cartItem := models.CartItem{ID: 5, ProductID: product.ID, UserID: "7", Quantity: 1, Product: product}
+
+	for _, tc := range []struct {
+		name     string
+		response interface{}
+		visible  bool
+	}{
+		{"product as user", FromProduct(&product, auth.RoleUser), false},
+		{"products as user", FromProducts([]models.Product{product}, auth.RoleUser), false},
+		{"invoice as user", FromInvoice(&invoice, auth.RoleUser), false},
+		{"cart item", FromCartItem(&cartItem), false},
+		{"product as operator", FromProduct(&product, auth.RoleOperator), true},
+		{"product as admin", FromProduct(&product, auth.RoleAdmin), true},
+		{"invoice as service", FromInvoice(&invoice, auth.RoleService), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := marshal(t, tc.response)
+			if got := strings.Contains(body, `"operator_id"`) || strings.Contains(body, productOperator); got != tc.visible {
+				t.Errorf("operator_id visible = %v, want %v: %s", got, tc.visible, body)
+			}
+		})
+	}
+}
+
+func TestStaffResponseCode(t *testing.T) {
+	operator := marshal(t, FromStaff(&models.Operator{OperatorID: "OP-1"}))
+	if !strings.Contains(operator, `"operator_id":"OP-1"`) || strings.Contains(operator, `"roles"`) {
+		t.Errorf("unexpected operator response: %s", operator)
+	}
+	admin := marshal(t, FromStaff(&models.Admin{AdminID: "AD-1", Roles: testRoles()}))
+	if !strings.Contains(admin, `"admin_id":"AD-1"`) || !strings.Contains(admin, `"products.write"`) {
+		t.Errorf("unexpected admin response: %s", admin)
+	}
+}
@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// IdentityResponse adalah akun identity provider (OIDC) yang ditautkan ke user
+type IdentityResponse struct {
+	ID          uint      `json:"id"`
+	Issuer      string    `json:"issuer"`
+	Subject     string    `json:"subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FromIdentities memetakan daftar identity milik satu user
+func FromIdentities(identities []models.UserIdentity) []IdentityResponse {
+	responses := make([]IdentityResponse, len(identities))
+	for i, identity := range identities {
+		responses[i] = IdentityResponse{
+			ID:          identity.ID,
+			Issuer:      identity.Issuer,
+			Subject:     identity.Subject,
+			Email:       identity.Email,
+			LastLoginAt: identity.LastLoginAt,
+			CreatedAt:   identity.CreatedAt,
+		}
+	}
+	return responses
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// InvoiceItemResponse adalah satu baris produk pada invoice
+type InvoiceItemResponse struct {
+	ID        int             `json:"id"`
+	InvoiceID int             `json:"invoice_id"`
+	ProductID int             `json:"product_id"`
+	Quantity  int             `json:"quantity"`
+	Price     float64         `json:"price"`
+	Total     float64         `json:"total"`
+	Product   ProductResponse `json:"product"`
+}
+
+// InvoiceResponse adalah invoice beserta item dan ringkasan customer-nya
+type InvoiceResponse struct {
+	ID             int               `json:"id"`
+	UserID         string            `json:"user_id"`
+	User           *CustomerResponse `json:"user,omitempty"`
+	TotalPrice     float64           `json:"total_price"`
+	CreatedAt      time.Time         `json:"created_at"`
+	Status         string            `json:"status"`
+	StatusShipment string            `json:"status_shipment"`
+	// ShippingAddress kosong (null) untuk invoice yang dibuat sebelum ada buku alamat
+	ShippingAddress *AddressResponse      `json:"shipping_address"`
+	InvoiceItems    []InvoiceItemResponse `json:"invoice_items"`
+}
+
+// FromInvoice memetakan invoice sesuai role yang melihatnya. User hanya disertakan
+// jika sudah di-preload.
+func FromInvoice(invoice *models.Invoice, role string) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:             invoice.ID,
+		UserID:         invoice.UserID,
+		TotalPrice:     invoice.TotalPrice,
+		CreatedAt:      invoice.CreatedAt,
+		Status:         invoice.Status,
+		StatusShipment: invoice.StatusShipment,
+		InvoiceItems:   make([]InvoiceItemResponse, len(invoice.InvoiceItems)),
+	}
+	if invoice.ShippingAddressID != nil {
+		address := FromAddress(&invoice.ShippingAddress)
+		response.ShippingAddress = &address
+	}
+	if invoice.User.ID != 0 {
+		customer := FromCustomer(&invoice.User)
+		response.User = &customer
+	}
+	for i := range invoice.InvoiceItems {
+		item := &invoice.InvoiceItems[i]
+		response.InvoiceItems[i] = InvoiceItemResponse{
+			ID:        item.ID,
+			InvoiceID: item.InvoiceID,
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     item.Price,
+			Total:     item.Total,
+			Product:   FromProduct(&item.Product, role),
+		}
+	}
+	return response
+}
+
+// FromInvoices memetakan daftar invoice sesuai role yang melihatnya
+func FromInvoices(invoices []models.Invoice, role string) []InvoiceResponse {
+	responses := make([]InvoiceResponse, len(invoices))
+	for i := range invoices {
+		responses[i] = FromInvoice(&invoices[i], role)
+	}
+	return responses
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "github.com/raihan1405/go-restapi/models"
+
+// ProductResponse adalah data produk di katalog. OperatorID hanya diisi untuk staff.
+type ProductResponse struct {
+	ID          int              `json:"id"`
+	ProductName string           `json:"productName"`
+	BrandName   string           `json:"brandName"`
+	Price       int              `json:"price"`
+	Status      bool             `json:"status"`
+	Quantity    int              `json:"quantity"`
+	Category    *CategorySummary `json:"category"`
+	OperatorID  string           `json:"operator_id,omitempty"`
+}
+
+// FromProduct memetakan produk sesuai role yang melihatnya
+func FromProduct(product *models.Product, role string) ProductResponse {
+	response := ProductResponse{
+		ID:          product.ID,
+		ProductName: product.ProductName,
+		BrandName:   product.BrandName,
+		Price:       product.Price,
+		Status:      product.Status,
+		Quantity:    product.Quantity,
+		Category:    FromCategorySummary(product.Category),
+	}
+	if staffView(role) {
+		response.OperatorID = product.OperatorID
+	}
+	return response
+}
+
+// FromProducts memetakan daftar produk sesuai role yang melihatnya
+func FromProducts(products []models.Product, role string) []ProductResponse {
+	responses := make([]ProductResponse, len(products))
+	for i := range products {
+		responses[i] = FromProduct(&products[i], role)
+	}
+	return responses
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// SigningKeyResponse adalah metadata kunci penandatangan JWT tanpa materi kuncinya
+type SigningKeyResponse struct {
+	ID        uint       `json:"id"`
+	KeyID     string     `json:"kid"`
+	Algorithm string     `json:"alg"`
+	NotBefore time.Time  `json:"not_before"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// FromSigningKey memetakan kunci penandatangan ke metadatanya
+func FromSigningKey(key *models.SigningKey) SigningKeyResponse {
+	return SigningKeyResponse{
+		ID:        key.ID,
+		KeyID:     key.KeyID,
+		Algorithm: key.Algorithm,
+		NotBefore: key.NotBefore,
+		RevokedAt: key.RevokedAt,
+		CreatedAt: key.CreatedAt,
+	}
+}
@@ -0,0 +1,155 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// PermissionResponse adalah satu permission yang bisa diberikan ke role
+type PermissionResponse struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// RoleResponse adalah role staff beserta permission-nya
+type RoleResponse struct {
+	ID          uint                 `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	BuiltIn     bool                 `json:"built_in"`
+	Permissions []PermissionResponse `json:"permissions"`
+}
+
+// staffResponse adalah field yang sama pada akun operator dan admin; hash
+// password dan secret MFA tidak pernah ikut
+type staffResponse struct {
+	ID                 uint           `json:"id"`
+	Name               string         `json:"name"`
+	Email              string         `json:"email"`
+	Phone              string         `json:"phone"`
+	MustChangePassword bool           `json:"must_change_password"`
+	Active             bool           `json:"active"`
+	Roles              []RoleResponse `json:"roles,omitempty"`
+	MFAEnabled         bool           `json:"mfa_enabled"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+// OperatorResponse adalah data akun operator
+type OperatorResponse struct {
+	OperatorID string `json:"operator_id"`
+	staffResponse
+}
+
+// AdminResponse adalah data akun admin
+type AdminResponse struct {
+	AdminID string `json:"admin_id"`
+	staffResponse
+}
+
+// FromPermissions memetakan daftar permission
+func FromPermissions(perms []models.Permission) []PermissionResponse {
+	responses := make([]PermissionResponse, len(perms))
+	for i, perm := range perms {
+		responses[i] = PermissionResponse{ID: perm.ID, Name: perm.Name, Description: perm.Description}
+	}
+	return responses
+}
+
+// FromRole memetakan role beserta permission yang sudah di-preload
+func FromRole(role *models.Role) RoleResponse {
+	return RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		BuiltIn:     role.BuiltIn,
+		Permissions: FromPermissions(role.Permissions),
+	}
+}
+
+// FromRoles memetakan daftar role
+func FromRoles(roles []models.Role) []RoleResponse {
+	responses := make([]RoleResponse, len(roles))
+	for i := range roles {
+		responses[i] = FromRole(&roles[i])
+	}
+	return responses
+}
+
+// fromStaffRoles mengembalikan nil jika role tidak di-preload agar field roles tidak tampil
+func fromStaffRoles(roles []models.Role) []RoleResponse {
+	if len(roles) == 0 {
+		return nil
+	}
+	return FromRoles(roles)
+}
+
+// FromOperator memetakan akun operator ke response-nya
+func FromOperator(operator *models.Operator) OperatorResponse {
+	return OperatorResponse{
+		OperatorID: operator.OperatorID,
+		staffResponse: staffResponse{
+			ID:                 operator.ID,
+			Name:               operator.Name,
+			Email:              operator.Email,
+			Phone:              operator.Phone,
+			MustChangePassword: operator.MustChangePassword,
+			Active:             operator.Active,
+			Roles:              fromStaffRoles(operator.Roles),
+			MFAEnabled:         operator.MFAEnabled,
+			CreatedAt:          operator.CreatedAt,
+			UpdatedAt:          operator.UpdatedAt,
+		},
+	}
+}
+
+// FromOperators memetakan daftar operator
+func FromOperators(operators []models.Operator) []OperatorResponse {
+	responses := make([]OperatorResponse, len(operators))
+	for i := range operators {
+		responses[i] = FromOperator(&operators[i])
+	}
+	return responses
+}
+
+// FromAdmin memetakan akun admin ke response-nya
+func FromAdmin(admin *models.Admin) AdminResponse {
+	return AdminResponse{
+		AdminID: admin.AdminID,
+		staffResponse: staffResponse{
+			ID:                 admin.ID,
+			Name:               admin.Name,
+			Email:              admin.Email,
+			Phone:              admin.Phone,
+			MustChangePassword: admin.MustChangePassword,
+			Active:             admin.Active,
+			Roles:              fromStaffRoles(admin.Roles),
+			MFAEnabled:         admin.MFAEnabled,
+			CreatedAt:          admin.CreatedAt,
+			UpdatedAt:          admin.UpdatedAt,
+		},
+	}
+}
+
+// FromAdmins memetakan daftar admin
+func FromAdmins(admins []models.Admin) []AdminResponse {
+	responses := make([]AdminResponse, len(admins))
+	for i := range admins {
+		responses[i] = FromAdmin(&admins[i])
+	}
+	return responses
+}
+
+// FromStaff memetakan akun dari handler yang melayani operator dan admin
+// sekaligus (*models.Operator atau *models.Admin)
+func FromStaff(account interface{}) interface{} {
+	switch account := account.(type) {
+	case *models.Operator:
+		return FromOperator(account)
+	case *models.Admin:
+		return FromAdmin(account)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+)
+
+// UserResponse adalah data profil customer tanpa hash password
+type UserResponse struct {
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	PhoneNumber     string     `json:"phoneNumber"`
+	Username        string     `json:"username"`
+	EmailVerified   bool       `json:"emailVerified"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	SuspendedAt     *time.Time `json:"suspendedAt,omitempty"`
+	CreatedAt       *time.Time `json:"createdAt"`
+}
+
+// CustomerResponse adalah ringkasan customer yang ditempelkan pada invoice
+type CustomerResponse struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phoneNumber"`
+}
+
+// FromUser memetakan user ke response profilnya
+func FromUser(user *models.User) UserResponse {
+	return UserResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		PhoneNumber:     user.PhoneNumber,
+		Username:        user.Username,
+		EmailVerified:   user.EmailVerified(),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		SuspendedAt:     user.SuspendedAt,
+		CreatedAt:       user.CreatedAt,
+	}
+}
+
+// FromCustomer memetakan user ke ringkasan customer
+func FromCustomer(user *models.User) CustomerResponse {
+	return CustomerResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+	}
+}
+
+// AdminUserResponse adalah profil customer beserta ringkasan pesanannya untuk admin.
+// OrderCount dan TotalSpent tidak menghitung invoice yang Rejected atau Cancelled.
+type AdminUserResponse struct {
+	UserResponse
+	OrderCount int64   `json:"orderCount"`
+	TotalSpent float64 `json:"totalSpent"`
+}
+
+// AdminUserDetailResponse adalah tampilan detail customer untuk admin
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	Invoices  []InvoiceResponse     `json:"invoices"`
+	Cart      []CartItemResponse    `json:"cart"`
+	Addresses []UserAddressResponse `json:"addresses"`
+}
+
+// FromAdminUser memetakan user beserta statistik pesanannya
+func FromAdminUser(user *models.User, orderCount int64, totalSpent float64) AdminUserResponse {
+	return AdminUserResponse{
+		UserResponse: FromUser(user),
+		OrderCount:   orderCount,
+		TotalSpent:   totalSpent,
+	}
+}
@@ -0,0 +1,84 @@
+// Package mailer mengirim email transaksional (verifikasi, reset password) lewat
+// implementasi yang bisa diganti: SMTP untuk produksi, file atau memori untuk
+// pengembangan lokal dan pengujian.
+package mailer
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+// Message adalah satu email teks biasa
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim Message ke penerimanya
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var (
+	mu      sync.RWMutex
+	current Mailer
+)
+
+// FromEnv memilih implementasi berdasarkan env MAILER: "smtp", "memory",
+// atau "file" (default, menulis ke direktori MAILER_DIR).
+func FromEnv() Mailer {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("MAIL_FROM"),
+		)
+	case "memory":
+		return NewMemoryMailer()
+	default:
+		dir := os.Getenv("MAILER_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return NewFileMailer(dir)
+	}
+}
+
+// Default mengembalikan mailer aktif. Dibuat dari env saat pertama kali dipakai
+// karena .env baru dimuat di main.
+func Default() Mailer {
+	mu.RLock()
+	m := current
+	mu.RUnlock()
+	if m != nil {
+		return m
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		current = FromEnv()
+	}
+	return current
+}
+
+// SetDefault mengganti mailer aktif, misalnya dengan MemoryMailer saat pengujian
+func SetDefault(m Mailer) {
+	mu.Lock()
+	current = m
+	mu.Unlock()
+}
+
+// Send mengirim pesan lewat mailer aktif
+func Send(msg Message) error {
+	if err := Default().Send(msg); err != nil {
+		log.Printf("Failed to send %q to %s: %v\n", msg.Subject, msg.To, err)
+		return err
+	}
+	return nil
+}
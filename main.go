@@ -9,10 +9,13 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/swagger"
 	"github.com/joho/godotenv"
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/db"
 	_ "github.com/raihan1405/go-restapi/docs"
 	"github.com/raihan1405/go-restapi/models"
+	"github.com/raihan1405/go-restapi/regions"
 	"github.com/raihan1405/go-restapi/routes"
+	"github.com/raihan1405/go-restapi/search"
 )
 
 func getPort() string {
@@ -28,6 +31,14 @@ func getPort() string {
 // @version		1.0
 // @description	This is a sample server celler server.
 // @termsOfService	http://swagger.io/terms/
+// @securityDefinitions.apikey	BearerAuth
+// @in							header
+// @name						Authorization
+// @description				Access token as "Bearer <token>"; browsers may use the session cookie instead
+// @securityDefinitions.apikey	APIKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				Admin-issued API key for machine integrations on operator routes
 func main() {
 
 	err := godotenv.Load()
@@ -40,11 +51,12 @@ func main() {
 		AllowOrigins:     "http://localhost:5173",
 		AllowCredentials: true,
 		AllowMethods:     "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Auth-Mode,X-CSRF-Token,X-API-Key",
 	}))
 
 	db.Init()
 	models.Setup(db.DB)
+	models.Role{}.Setup(db.DB)
 	models.Operator{}.Setup(db.DB)
 	models.CartItem{}.Setup(db.DB)
 	models.Invoice{}.Setup(db.DB)
@@ -53,6 +65,45 @@ func main() {
 	models.ProductReport{}.Setup(db.DB)
 	models.ProductIn{}.Setup(db.DB)
 	models.ProductOut{}.Setup(db.DB)
+	models.Session{}.Setup(db.DB)
+	models.RefreshToken{}.Setup(db.DB)
+	models.AuditLog{}.Setup(db.DB)
+	models.EmailVerificationToken{}.Setup(db.DB)
+	models.PasswordResetToken{}.Setup(db.DB)
+	models.LoginAttempt{}.Setup(db.DB)
+	models.MFARecoveryCode{}.Setup(db.DB)
+	models.Setting{}.Setup(db.DB)
+	models.APIKey{}.Setup(db.DB)
+	models.UserIdentity{}.Setup(db.DB)
+	models.Province{}.Setup(db.DB)
+	models.UserAddress{}.Setup(db.DB)
+	models.SigningKey{}.Setup(db.DB)
+
+	// Impor data wilayah (REGIONS_DATA_FILE, atau contoh bawaan saat development) jika berubah sejak startup terakhir.
+	// Tanpa data wilayah alamat customer tidak bisa divalidasi, jadi di luar development
+	// setiap kegagalan import menghentikan server.
+	if err := regions.Sync(db.DB); err != nil {
+		if !models.Development() {
+			log.Fatalf("Cannot import region data: %v\n", err)
+		}
+		log.Printf("Cannot import region data: %v\n", err)
+	}
+
+	// Bangun index pencarian produk; diperbarui setiap jam di background
+	search.Start(db.DB)
+
+	// Private key JWT hanya boleh disimpan tanpa enkripsi saat development
+	if err := auth.CheckKeyEncryption(); err != nil {
+		log.Fatalf("Cannot store signing keys: %v\n", err)
+	}
+
+	// Secret TOTP staff yang masih tersimpan tanpa enkripsi dienkripsi dengan kunci yang sama
+	if err := auth.SealMFASecrets(); err != nil {
+		log.Printf("Cannot encrypt MFA secrets: %v\n", err)
+	}
+
+	// Buat kunci JWT pertama bila perlu dan rotasi sesuai jadwal di background
+	auth.StartKeyRotation()
 
 
 	routes.Setup(app)
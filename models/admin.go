@@ -12,6 +12,14 @@ type Admin struct {
 	Name       string         `json:"name"`
 	Email      string         `gorm:"unique" json:"email"`
 	Phone      string         `json:"phone"`
+	Password   []byte         `json:"-"`
+	MustChangePassword bool   `json:"must_change_password"`
+	Active     bool           `gorm:"not null;default:true" json:"active"`
+	Roles      []Role         `gorm:"many2many:admin_roles" json:"roles,omitempty"`
+	MFASecret  string         `gorm:"size:128" json:"-"` // secret TOTP base32 (terenkripsi, lihat auth.SealSecret), terisi sejak enrollment dimulai
+	MFAEnabled bool           `gorm:"not null;default:false" json:"mfa_enabled"`
+	MFALastStep int64         `json:"-"` // langkah TOTP terakhir yang dipakai, mencegah replay
+	MFAChallengeCounter int64 `gorm:"not null;default:0" json:"-"` // dinaikkan setiap login MFA berhasil agar token tantangan sekali pakai
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 
@@ -19,4 +27,7 @@ type Admin struct {
 
 func (Admin) Setup(db *gorm.DB) {
 	db.AutoMigrate(&Admin{})
+	bootstrapAdminPasswords(db)
+	reportStaffWithoutPassword(db, &Admin{}, "admin")
+	seedStaffRoles(db, "admins", "admin_roles", "admin_id", RoleNameAdmin)
 }
\ No newline at end of file
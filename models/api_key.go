@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKey adalah kredensial integrasi mesin (scanner gudang, sinkronisasi ERP)
+// yang dibuat admin. Hanya hash key yang disimpan; key asli ditampilkan sekali
+// saat dibuat. Permissions adalah scope eksplisit key tersebut.
+type APIKey struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"size:100;not null" json:"name"`
+	Prefix      string       `gorm:"size:16;index" json:"prefix"`
+	KeyHash     string       `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Permissions []Permission `gorm:"many2many:api_key_permissions" json:"permissions"`
+	// RateLimit adalah jumlah request maksimum per menit untuk key ini
+	RateLimit   int        `gorm:"not null;default:60" json:"rate_limit"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:64" json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	CreatedByID uint       `json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Active menandakan key belum dicabut dan belum kedaluwarsa pada waktu now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+func (APIKey) Setup(db *gorm.DB) {
+	db.AutoMigrate(&APIKey{})
+}
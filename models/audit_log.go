@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog mencatat setiap perubahan administratif beserta pelakunya
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorRole  string    `gorm:"size:16" json:"actor_role"`
+	ActorID    uint      `json:"actor_id"`
+	Action     string    `gorm:"size:64;index" json:"action"`
+	TargetType string    `gorm:"size:32;index:idx_audit_logs_target" json:"target_type"`
+	TargetID   uint      `gorm:"index:idx_audit_logs_target" json:"target_id"`
+	Details    string    `gorm:"type:text" json:"details"` // JSON berisi field yang berubah
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (AuditLog) Setup(db *gorm.DB) {
+	db.AutoMigrate(&AuditLog{})
+}
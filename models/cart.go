@@ -9,7 +9,7 @@ import (
 type CartItem struct {
 	ID        int    `json:"id"`
 	ProductID int    `json:"productId"`
-	UserID    string `json:"userId"`
+	UserID    string `gorm:"size:191;index" json:"userId"` // diindeks agar checkout hanya mengunci keranjang user sendiri
 	Quantity  int    `json:"quantity"`
 	Product   Product   `gorm:"foreignKey:ProductID"`
 	TotalPrice float64   `json:"total_price" gorm:"-"`
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Category adalah kategori produk. Kategori bisa bersarang lewat ParentID;
+// produk di subkategori ikut tampil saat kategori induknya dibuka.
+type Category struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ParentID  *uint     `gorm:"index" json:"parent_id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Slug      string    `gorm:"size:120;uniqueIndex;not null" json:"slug"`
+	SortOrder int       `gorm:"not null;default:0" json:"sort_order"`
+	Active    bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Slugify membuat slug huruf kecil dari nama: huruf dan angka dipertahankan,
+// karakter lain menjadi tanda hubung ("Sepatu & Sandal" -> "sepatu-sandal")
+func Slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// CategoryDescendants mengembalikan ID kategori beserta seluruh turunannya.
+// Jika activeOnly, cabang yang tidak aktif ikut dilewati.
+func CategoryDescendants(db *gorm.DB, id uint, activeOnly bool) ([]uint, error) {
+	var categories []Category
+	if err := db.Select("id", "parent_id", "active").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	children := map[uint][]uint{}
+	for _, category := range categories {
+		if category.ParentID != nil && (category.Active || !activeOnly) {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// migrateLegacyCategories memetakan kolom teks category lama ke tabel categories.
+// Nama yang slug-nya sama ("Shoes" dan "shoes") digabung menjadi satu kategori;
+// nama berbeda untuk hal yang sama ("Sepatu") digabung admin dengan menghapus
+// kategori dan memindahkan produknya.
+func migrateLegacyCategories(db *gorm.DB) {
+	// Index lama (category, price) diganti index pada category_id
+	if db.Migrator().HasIndex(&Product{}, "idx_products_category_price") {
+		db.Migrator().DropIndex(&Product{}, "idx_products_category_price")
+	}
+
+	var names []string
+	if err := db.Model(&Product{}).Where("category_id IS NULL AND category <> ''").Distinct().Pluck("category", &names).Error; err != nil {
+		log.Printf("Cannot read legacy product categories: %v\n", err)
+		return
+	}
+
+	for _, name := range names {
+		slug := Slugify(name)
+		if slug == "" {
+			continue
+		}
+
+		category := Category{Name: strings.TrimSpace(name), Slug: slug, Active: true}
+		if err := db.Where(Category{Slug: slug}).FirstOrCreate(&category).Error; err != nil {
+			log.Printf("Cannot create category %q: %v\n", name, err)
+			continue
+		}
+		if err := db.Model(&Product{}).Where("category_id IS NULL AND category = ?", name).Update("category_id", category.ID).Error; err != nil {
+			log.Printf("Cannot move products to category %q: %v\n", name, err)
+		}
+	}
+	if len(names) > 0 {
+		log.Printf("Migrated %d legacy product categories\n", len(names))
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EmailVerificationToken adalah token sekali pakai yang dikirim ke email user.
+// Hanya hash SHA-256 token yang disimpan.
+type EmailVerificationToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    int        `gorm:"index" json:"user_id"`
+	Email     string     `json:"email"` // alamat yang diverifikasi token ini
+	TokenHash string     `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (EmailVerificationToken) Setup(db *gorm.DB) {
+	db.AutoMigrate(&EmailVerificationToken{})
+}
@@ -5,6 +5,26 @@ import (
 	"gorm.io/gorm"
 )
 
+// Status invoice selama siklus hidupnya. Stok direservasi saat invoice dibuat
+// (Pending), dicatat sebagai ProductOut saat Approved, dan dikembalikan saat
+// Rejected atau Cancelled.
+const (
+	InvoiceStatusPending   = "Pending"
+	InvoiceStatusApproved  = "Approved"
+	InvoiceStatusRejected  = "Rejected"
+	InvoiceStatusCancelled = "Cancelled"
+)
+
+// Status pengiriman invoice. Cancelled dan Returned bersifat final: stok invoice
+// dikembalikan dan statusnya tidak bisa diubah lagi.
+const (
+	ShipmentStatusPending   = "Pending"
+	ShipmentStatusShipped   = "Shipped"
+	ShipmentStatusDelivered = "Delivered"
+	ShipmentStatusReturned  = "Returned"
+	ShipmentStatusCancelled = "Cancelled"
+)
+
 // InvoiceDetailResponse mewakili response untuk detail invoice
 type InvoiceDetailResponse struct {
 	Invoice      Invoice        `json:"invoice"`
@@ -20,6 +40,14 @@ type Invoice struct {
 	CreatedAt   time.Time     `json:"created_at"`
 	Status      string        `json:"status"`
 	StatusShipment string       `json:"status_shipment"`
+	// StockHeld menandakan stok item invoice sedang dikurangi dari products.quantity
+	// (direservasi atau sudah keluar). Invoice lama dari sebelum reservasi stok
+	// bernilai false sehingga pembatalannya tidak menambah stok yang tidak pernah diambil.
+	StockHeld   bool          `gorm:"not null;default:false" json:"-"`
+	// ShippingAddress adalah salinan alamat saat invoice dibuat; tidak ikut berubah
+	// jika alamat di buku alamat diedit atau dihapus. Kosong untuk invoice lama.
+	ShippingAddressID *uint   `json:"shipping_address_id"`
+	ShippingAddress   Address `json:"shipping_address" gorm:"embedded;embeddedPrefix:shipping_"`
 	InvoiceItems []InvoiceItem `json:"invoice_items" gorm:"foreignkey:InvoiceID"`
 }
 
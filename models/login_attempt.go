@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LoginAttempt menghitung login gagal berturut-turut untuk satu kunci
+// (akun per role, atau alamat IP) beserta batas waktu lockout-nya.
+type LoginAttempt struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Key           string     `gorm:"column:throttle_key;size:191;uniqueIndex" json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (LoginAttempt) Setup(db *gorm.DB) {
+	db.AutoMigrate(&LoginAttempt{})
+}
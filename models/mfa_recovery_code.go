@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MFARecoveryCode adalah kode cadangan sekali pakai untuk login staff jika
+// perangkat authenticator hilang. Hanya hash SHA-256 kode yang disimpan.
+type MFARecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Role      string     `gorm:"size:16;index:idx_mfa_recovery_codes_subject" json:"role"`
+	SubjectID uint       `gorm:"index:idx_mfa_recovery_codes_subject" json:"subject_id"`
+	CodeHash  string     `gorm:"size:64" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (MFARecoveryCode) Setup(db *gorm.DB) {
+	db.AutoMigrate(&MFARecoveryCode{})
+}
@@ -12,6 +12,14 @@ type Operator struct {
 	Name       string         `json:"name"`
 	Email      string         `gorm:"unique" json:"email"`
 	Phone      string         `json:"phone"`
+	Password   []byte         `json:"-"`
+	MustChangePassword bool   `json:"must_change_password"`
+	Active     bool           `gorm:"not null;default:true" json:"active"`
+	Roles      []Role         `gorm:"many2many:operator_roles" json:"roles,omitempty"`
+	MFASecret  string         `gorm:"size:128" json:"-"` // secret TOTP base32 (terenkripsi, lihat auth.SealSecret), terisi sejak enrollment dimulai
+	MFAEnabled bool           `gorm:"not null;default:false" json:"mfa_enabled"`
+	MFALastStep int64         `json:"-"` // langkah TOTP terakhir yang dipakai, mencegah replay
+	MFAChallengeCounter int64 `gorm:"not null;default:0" json:"-"` // dinaikkan setiap login MFA berhasil agar token tantangan sekali pakai
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 
@@ -19,4 +27,6 @@ type Operator struct {
 
 func (Operator) Setup(db *gorm.DB) {
 	db.AutoMigrate(&Operator{})
+	reportStaffWithoutPassword(db, &Operator{}, "operator")
+	seedStaffRoles(db, "operators", "operator_roles", "operator_id", RoleNameOperator)
 }
\ No newline at end of file
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Nama permission yang bisa diberikan ke role staff
+const (
+	PermissionDashboardView    = "dashboard.view"
+	PermissionProductsView     = "products.view"
+	PermissionProductsEdit     = "products.edit"
+	PermissionInvoicesView     = "invoices.view"
+	PermissionInvoicesApprove  = "invoices.approve"
+	PermissionInvoicesShip     = "invoices.ship"
+	PermissionReportsView      = "reports.view"
+	PermissionStaffManage      = "staff.manage"
+	PermissionRolesManage      = "roles.manage"
+	PermissionSessionsManage   = "sessions.manage"
+	PermissionAuditView        = "audit.view"
+	PermissionLoginsUnlock     = "logins.unlock"
+	PermissionSettingsManage   = "settings.manage"
+	PermissionAPIKeysManage    = "apikeys.manage"
+	PermissionUsersView        = "users.view"
+	PermissionUsersManage      = "users.manage"
+	PermissionCategoriesManage = "categories.manage"
+)
+
+// Nama role bawaan yang dibuat saat startup
+const (
+	RoleNameOperator  = "operator"
+	RoleNameWarehouse = "warehouse"
+	RoleNameAdmin     = "admin"
+)
+
+// Permission adalah satu hak akses yang dicek oleh route
+type Permission struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `gorm:"size:64;uniqueIndex;not null" json:"name"`
+	Description string `json:"description"`
+}
+
+// Role adalah kumpulan permission yang bisa diberikan ke operator dan admin.
+// Role bawaan (BuiltIn) disinkronkan setiap startup dan tidak bisa diubah lewat API.
+type Role struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	Name        string       `gorm:"size:64;uniqueIndex;not null" json:"name"`
+	Description string       `json:"description"`
+	BuiltIn     bool         `gorm:"not null;default:false" json:"built_in"`
+	Permissions []Permission `gorm:"many2many:role_permissions" json:"permissions"`
+}
+
+// PermissionCatalog adalah daftar semua permission yang dikenal aplikasi
+var PermissionCatalog = []Permission{
+	{Name: PermissionDashboardView, Description: "View the operator dashboard of the logged-in operator"},
+	{Name: PermissionProductsView, Description: "View the product catalog"},
+	{Name: PermissionProductsEdit, Description: "Add products and change stock"},
+	{Name: PermissionInvoicesView, Description: "View customer invoices"},
+	{Name: PermissionInvoicesApprove, Description: "Approve or reject pending invoices"},
+	{Name: PermissionInvoicesShip, Description: "Update shipment status of invoices"},
+	{Name: PermissionReportsView, Description: "View product stock reports"},
+	{Name: PermissionStaffManage, Description: "Create, update and deactivate operators and admins"},
+	{Name: PermissionRolesManage, Description: "Manage roles and assign them to staff"},
+	{Name: PermissionSessionsManage, Description: "Revoke sessions of other accounts"},
+	{Name: PermissionAuditView, Description: "View the audit log"},
+	{Name: PermissionLoginsUnlock, Description: "Unlock accounts locked after failed logins"},
+	{Name: PermissionSettingsManage, Description: "Change application settings such as mandatory MFA"},
+	{Name: PermissionAPIKeysManage, Description: "Create and revoke API keys for machine integrations"},
+	{Name: PermissionUsersView, Description: "Search customers and view their orders and carts"},
+	{Name: PermissionUsersManage, Description: "Edit, suspend and reinstate customer accounts"},
+	{Name: PermissionCategoriesManage, Description: "Create, update and delete product categories"},
+}
+
+// builtInRoles menentukan permission untuk setiap role bawaan
+var builtInRoles = []struct {
+	Name        string
+	Description string
+	Permissions []string
+}{
+	{
+		Name:        RoleNameOperator,
+		Description: "Full operator: stock, invoice approval and shipping",
+		Permissions: []string{PermissionDashboardView, PermissionProductsView, PermissionProductsEdit, PermissionInvoicesView, PermissionInvoicesApprove, PermissionInvoicesShip},
+	},
+	{
+		Name:        RoleNameWarehouse,
+		Description: "Warehouse staff: manage stock and shipping, cannot approve invoices",
+		Permissions: []string{PermissionDashboardView, PermissionProductsView, PermissionProductsEdit, PermissionInvoicesView, PermissionInvoicesShip},
+	},
+	{
+		Name:        RoleNameAdmin,
+		Description: "Administrator with every permission",
+	},
+}
+
+// Setup memigrasi tabel permission dan role lalu menyinkronkan role bawaan.
+// Harus dipanggil sebelum Operator dan Admin di-setup.
+func (Role) Setup(db *gorm.DB) {
+	db.AutoMigrate(&Permission{}, &Role{})
+
+	// Dashboard dulu terbuka untuk semua operator; role yang sudah ada ikut
+	// mendapat permission-nya saat permission itu pertama kali dibuat
+	var dashboardExists int64
+	db.Model(&Permission{}).Where("name = ?", PermissionDashboardView).Count(&dashboardExists)
+
+	for i := range PermissionCatalog {
+		perm := PermissionCatalog[i]
+		db.Where(Permission{Name: perm.Name}).Assign(Permission{Description: perm.Description}).FirstOrCreate(&perm)
+	}
+
+	if dashboardExists == 0 {
+		if err := db.Exec(
+			"INSERT INTO role_permissions (role_id, permission_id) "+
+				"SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = ?",
+			PermissionDashboardView,
+		).Error; err != nil {
+			log.Printf("Cannot grant %s to existing roles: %v\n", PermissionDashboardView, err)
+		}
+	}
+
+	for _, builtIn := range builtInRoles {
+		var perms []Permission
+		query := db.Model(&Permission{})
+		if builtIn.Permissions != nil {
+			query = query.Where("name IN ?", builtIn.Permissions)
+		}
+		if err := query.Find(&perms).Error; err != nil {
+			log.Printf("Cannot load permissions for role %s: %v\n", builtIn.Name, err)
+			continue
+		}
+
+		var role Role
+		db.Where(Role{Name: builtIn.Name}).
+			Assign(Role{Description: builtIn.Description, BuiltIn: true}).
+			FirstOrCreate(&role)
+		if err := db.Model(&role).Association("Permissions").Replace(perms); err != nil {
+			log.Printf("Cannot sync permissions for role %s: %v\n", builtIn.Name, err)
+		}
+	}
+}
+
+// seedStaffRoles memberi role bawaan kepada staff yang belum punya role sama sekali,
+// sehingga akun lama tetap bisa mengakses route yang sekarang memerlukan permission.
+func seedStaffRoles(db *gorm.DB, staffTable string, joinTable string, column string, roleName string) {
+	err := db.Exec(
+		"INSERT INTO "+joinTable+" ("+column+", role_id) "+
+			"SELECT s.id, r.id FROM "+staffTable+" s JOIN roles r ON r.name = ? "+
+			"WHERE NOT EXISTS (SELECT 1 FROM "+joinTable+" j WHERE j."+column+" = s.id)",
+		roleName,
+	).Error
+	if err != nil {
+		log.Printf("Cannot assign default role to %s: %v\n", staffTable, err)
+	}
+}
@@ -1,13 +1,29 @@
 package models
 
 
+// Index katalog: FULLTEXT untuk pencarian kata kunci pada nama dan merek, serta
+// index biasa untuk filter kategori, merek dan harga. Kolom teks yang di-index
+// dibatasi 191 karakter agar muat di index utf8mb4.
 type Product struct{
 	ID int    `json:"id"`
-	ProductName string `json:"productName"`
-	BrandName string `json:"brandName"`
-	Price int `json:"price"`
+	ProductName string `json:"productName" gorm:"size:191;index:idx_products_search,class:FULLTEXT,priority:1"`
+	BrandName string `json:"brandName" gorm:"size:191;index;index:idx_products_search,class:FULLTEXT,priority:2"`
+	Price int `json:"price" gorm:"index;index:idx_products_category_id_price,priority:2"`
 	Status bool `json:"status"`
 	Quantity int `json:"quantity"`
-	Category    string `json:"Category"`
+	CategoryID  *uint     `json:"category_id" gorm:"index:idx_products_category_id_price,priority:1"`
+	Category    *Category `json:"category,omitempty"`
+	// LegacyCategory adalah kategori teks bebas sebelum ada tabel categories;
+	// hanya dibaca oleh migrasi saat startup
+	LegacyCategory string `json:"-" gorm:"column:category;size:191"`
 	OperatorID  string `json:"operator_id"`
 }
+
+// CategoryName mengembalikan nama kategori yang sudah di-preload, atau kategori
+// teks lama untuk produk yang belum dimigrasi
+func (p *Product) CategoryName() string {
+	if p.Category != nil {
+		return p.Category.Name
+	}
+	return p.LegacyCategory
+}
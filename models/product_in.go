@@ -11,6 +11,7 @@ type ProductIn struct {
 	ProductID int      `json:"productId"` // ID produk yang masuk
 	Quantity  int       `json:"quantity"`  // Jumlah yang masuk
 	OperatorID  string `json:"operator_id"`
+	InvoiceID *int      `json:"invoiceId"` // Invoice asal, jika masuk karena pesanan dibatalkan atau diretur
 	CreatedAt time.Time `json:"createdAt"` // Waktu transaksi
 }
 
@@ -11,6 +11,7 @@ type ProductOut struct {
 	ProductID uint      `json:"productId"` // ID produk yang keluar
 	Quantity  int       `json:"quantity"`  // Jumlah yang keluar
 	OperatorID  string `json:"operator_id"`
+	InvoiceID *int      `json:"invoiceId"` // Invoice asal, jika keluar karena penjualan
 	CreatedAt time.Time `json:"createdAt"` // Waktu transaksi
 }
 
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken menyimpan hash refresh token. Token dirotasi setiap dipakai;
+// semua token hasil rotasi dari satu login berbagi SessionID sehingga seluruh
+// sesi bisa dicabut sekaligus saat pemakaian ulang terdeteksi atau saat logout.
+type RefreshToken struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Role         string     `gorm:"size:16;index:idx_refresh_tokens_subject" json:"role"`
+	SubjectID    uint       `gorm:"index:idx_refresh_tokens_subject" json:"subject_id"`
+	SessionID    uint       `gorm:"index" json:"session_id"`
+	TokenHash    string     `gorm:"size:64;uniqueIndex" json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uint      `json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (RefreshToken) Setup(db *gorm.DB) {
+	db.AutoMigrate(&RefreshToken{})
+}
@@ -0,0 +1,40 @@
+package models
+
+import "gorm.io/gorm"
+
+// Wilayah administratif Indonesia memakai kode Kemendagri sebagai ID, misalnya
+// provinsi "31", kota "31.71", kecamatan "31.71.01" dan kelurahan "31.71.01.1001".
+// Data diimpor oleh package regions; aplikasi hanya membacanya.
+
+// Province adalah provinsi
+type Province struct {
+	ID   string `gorm:"primaryKey;size:13" json:"id"`
+	Name string `gorm:"size:100;not null" json:"name"`
+}
+
+// City adalah kabupaten atau kota
+type City struct {
+	ID         string `gorm:"primaryKey;size:13" json:"id"`
+	ProvinceID string `gorm:"size:13;index;not null" json:"province_id"`
+	Name       string `gorm:"size:100;not null" json:"name"`
+}
+
+// District adalah kecamatan
+type District struct {
+	ID     string `gorm:"primaryKey;size:13" json:"id"`
+	CityID string `gorm:"size:13;index;not null" json:"city_id"`
+	Name   string `gorm:"size:100;not null" json:"name"`
+}
+
+// Village adalah kelurahan atau desa beserta kode posnya
+type Village struct {
+	ID         string `gorm:"primaryKey;size:13" json:"id"`
+	DistrictID string `gorm:"size:13;index;not null" json:"district_id"`
+	Name       string `gorm:"size:100;not null" json:"name"`
+	PostalCode string `gorm:"size:5" json:"postal_code"`
+}
+
+// Setup memigrasi keempat tabel wilayah
+func (Province) Setup(db *gorm.DB) {
+	db.AutoMigrate(&Province{}, &City{}, &District{}, &Village{})
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session mewakili satu login (perangkat) milik user, operator atau admin.
+// Token akses membawa ID sesi sehingga sesi yang dicabut langsung ditolak
+// oleh middleware, dan semua refresh token sesi ikut dicabut.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Role       string     `gorm:"size:16;index:idx_sessions_subject" json:"role"`
+	SubjectID  uint       `gorm:"index:idx_sessions_subject" json:"subject_id"`
+	Device     string     `json:"device"`
+	IP         string     `gorm:"size:64" json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	// CSRFTokenHash adalah hash SHA-256 token CSRF sesi, dirotasi setiap refresh
+	CSRFTokenHash string `gorm:"size:64" json:"-"`
+}
+
+func (Session) Setup(db *gorm.DB) {
+	db.AutoMigrate(&Session{})
+}
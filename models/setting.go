@@ -0,0 +1,56 @@
+package models
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Key pengaturan aplikasi yang bisa diubah admin saat runtime
+const (
+	SettingMFARequired = "mfa.required_for_staff"
+)
+
+// EnvironmentEnv adalah lingkungan aplikasi (development, test atau production)
+const EnvironmentEnv = "APP_ENV"
+
+// Development menandakan server berjalan di development atau test, tempat
+// pengaman untuk production (data wilayah lengkap, enkripsi kunci) boleh dilonggarkan
+func Development() bool {
+	switch strings.ToLower(os.Getenv(EnvironmentEnv)) {
+	case "development", "dev", "test":
+		return true
+	}
+	return false
+}
+
+// Setting menyimpan satu pengaturan aplikasi dalam bentuk key/value
+type Setting struct {
+	Key       string    `gorm:"primaryKey;size:64" json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (Setting) Setup(db *gorm.DB) {
+	db.AutoMigrate(&Setting{})
+}
+
+// SettingBool membaca pengaturan boolean; nilai yang tidak ada dianggap false
+func SettingBool(db *gorm.DB, key string) (bool, error) {
+	var setting Setting
+	err := db.Where("`key` = ?", key).Limit(1).Find(&setting).Error
+	if err != nil || setting.Key == "" {
+		return false, err
+	}
+	return strconv.ParseBool(setting.Value)
+}
+
+// SetSetting menyimpan atau mengganti nilai pengaturan
+func SetSetting(db *gorm.DB, key string, value string) error {
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&Setting{Key: key, Value: value}).Error
+}
@@ -3,8 +3,19 @@ package models
 import "gorm.io/gorm"
 
 func Setup(db *gorm.DB) {
+	// User yang terdaftar sebelum ada verifikasi email dianggap sudah terverifikasi
+	grandfatherVerified := db.Migrator().HasTable(&User{}) && !db.Migrator().HasColumn(&User{}, "EmailVerifiedAt")
+
+	prepareUniqueEmail(db)
+
 	db.AutoMigrate(
 		&User{},
+		&Category{},
 		&Product{},
 	)
+	migrateLegacyCategories(db)
+
+	if grandfatherVerified {
+		db.Model(&User{}).Where("email_verified_at IS NULL").Update("email_verified_at", gorm.Expr("CURRENT_TIMESTAMP"))
+	}
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SigningKey adalah pasangan kunci asimetris untuk menandatangani JWT. Kunci
+// dengan NotBefore terbaru yang sudah lewat dipakai untuk menandatangani; kunci
+// lama tetap dipublikasikan di JWKS sampai token terakhirnya kedaluwarsa.
+// PrivateKey disimpan dalam PKCS#8 DER, terenkripsi AES-GCM jika Encrypted.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	KeyID      string     `gorm:"size:64;uniqueIndex;not null" json:"kid"`
+	Algorithm  string     `gorm:"size:16;not null" json:"alg"`
+	PrivateKey []byte     `gorm:"not null" json:"-"`
+	Encrypted  bool       `gorm:"not null;default:false" json:"-"`
+	PublicKey  []byte     `gorm:"not null" json:"-"`
+	NotBefore  time.Time  `gorm:"uniqueIndex;not null" json:"not_before"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (SigningKey) Setup(db *gorm.DB) {
+	db.AutoMigrate(&SigningKey{})
+}
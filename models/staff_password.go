@@ -0,0 +1,71 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const staffWithoutPassword = "password IS NULL OR password = ''"
+
+// reportStaffWithoutPassword mencatat jumlah operator/admin yang belum memiliki
+// password. Akun tersebut tidak bisa login sampai admin mengisi password-nya
+// lewat UpdateOperator/UpdateAdmin; tidak ada password bawaan bersama.
+func reportStaffWithoutPassword(db *gorm.DB, model interface{}, table string) {
+	var count int64
+	if err := db.Model(model).Where(staffWithoutPassword).Count(&count).Error; err != nil || count == 0 {
+		return
+	}
+	log.Printf("%d %s account(s) have no password and cannot log in until an admin sets one\n", count, table)
+}
+
+// bootstrapAdminPasswords menangani kasus belum ada satu pun admin yang bisa
+// login, sehingga tidak ada yang bisa mengisi password lewat UpdateAdmin. Setiap
+// admin tanpa password diberi password sekali pakai yang acak dan berbeda,
+// dicetak sekali ke log server dan wajib diganti saat login pertama.
+func bootstrapAdminPasswords(db *gorm.DB) {
+	var usable int64
+	if err := db.Model(&Admin{}).Not(staffWithoutPassword).Count(&usable).Error; err != nil || usable > 0 {
+		return
+	}
+
+	var admins []Admin
+	if err := db.Where(staffWithoutPassword).Find(&admins).Error; err != nil {
+		log.Printf("Cannot load admins without password: %v\n", err)
+		return
+	}
+
+	for _, admin := range admins {
+		password, err := oneTimePassword()
+		if err != nil {
+			log.Printf("Cannot generate one-time password for admin %s: %v\n", admin.AdminID, err)
+			continue
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+		if err != nil {
+			log.Printf("Cannot hash one-time password for admin %s: %v\n", admin.AdminID, err)
+			continue
+		}
+
+		// Syarat password kosong mencegah menimpa password yang diisi instance lain
+		result := db.Model(&Admin{}).Where("id = ?", admin.ID).Where(staffWithoutPassword).Updates(map[string]interface{}{
+			"password":             hash,
+			"must_change_password": true,
+		})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		log.Printf("No admin could log in: one-time password for admin %s is %s (must be changed at first login)\n", admin.AdminID, password)
+	}
+}
+
+func oneTimePassword() (string, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -1,10 +1,68 @@
 package models
 
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
 	ID          int    `json:"id"`
-	Email       string `json:"email" validate:"required,email"`
+	// Email unik, termasuk pada akun yang dihapus (emailnya sudah dianonimkan)
+	Email       string `json:"email" validate:"required,email" gorm:"size:191;uniqueIndex:idx_users_email"`
 	PhoneNumber string `json:"phoneNumber" validate:"required"`
 	Username    string `json:"username" validate:"required"`
-	Password    []byte `json:"password" validate:"required"`
+	Password    []byte `json:"-" validate:"required"`
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt"`
+	// SuspendedAt diisi admin untuk memblokir login tanpa menghapus akun
+	SuspendedAt *time.Time `json:"suspendedAt"`
+	// CreatedAt kosong untuk user yang terdaftar sebelum kolom ini ada
+	CreatedAt *time.Time `json:"createdAt"`
+	// DeletedAt menandai akun yang dihapus pemiliknya. Data pribadinya sudah
+	// dianonimkan; baris tetap ada agar invoice lama masih punya relasi user.
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	Invoices  []Invoice `json:"invoices" gorm:"foreignkey:UserID"`
 }
+
+// EmailVerified menandakan user sudah mengonfirmasi alamat emailnya
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Suspended menandakan akun sedang diblokir admin
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// DeletedUserEmail adalah email pengganti untuk akun yang sudah dihapus.
+// Domain .invalid tidak pernah bisa menerima email.
+func DeletedUserEmail(id int) string {
+	return fmt.Sprintf("deleted-%d@deleted.invalid", id)
+}
+
+// prepareUniqueEmail menyiapkan data lama sebelum index unik users.email dibuat.
+// Akun terhapus yang emailnya belum dianonimkan diberi email pengganti sehingga
+// alamatnya bisa dipakai mendaftar lagi. Duplikat di antara akun aktif tidak
+// bisa digabung otomatis, jadi startup dihentikan sampai admin membereskannya.
+func prepareUniqueEmail(db *gorm.DB) {
+	if !db.Migrator().HasTable(&User{}) || db.Migrator().HasIndex(&User{}, "idx_users_email") {
+		return
+	}
+
+	if err := db.Unscoped().Model(&User{}).
+		Where("deleted_at IS NOT NULL AND email NOT LIKE ?", "deleted-%@deleted.invalid").
+		Update("email", gorm.Expr("CONCAT('deleted-', id, '@deleted.invalid')")).Error; err != nil {
+		log.Fatalf("Cannot anonymize emails of deleted users: %v\n", err)
+	}
+
+	var duplicates []string
+	if err := db.Model(&User{}).Group("email").Having("COUNT(*) > 1").Pluck("email", &duplicates).Error; err != nil {
+		log.Fatalf("Cannot check duplicate user emails: %v\n", err)
+	}
+	if len(duplicates) > 0 {
+		log.Fatalf("Cannot add unique index on users.email, these emails belong to more than one account: %s\n", strings.Join(duplicates, ", "))
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Address adalah isi alamat pengiriman. Dipakai di buku alamat customer dan
+// disalin apa adanya ke invoice sebagai snapshot. Kode wilayah merujuk tabel
+// Province/City/District/Village sehingga aturan ongkir bisa memakai kodenya;
+// nama wilayah ikut disalin agar snapshot tetap terbaca walau data wilayah
+// diperbarui. Alamat yang dibuat sebelum ada data wilayah tidak punya kode.
+type Address struct {
+	RecipientName string `gorm:"size:100" json:"recipient_name"`
+	Phone         string `gorm:"size:30" json:"phone"`
+	Street        string `gorm:"size:255" json:"street"`
+	VillageID     string `gorm:"size:13;index" json:"village_id"`
+	Village       string `gorm:"size:100" json:"village"`
+	DistrictID    string `gorm:"size:13" json:"district_id"`
+	District      string `gorm:"size:100" json:"district"`
+	CityID        string `gorm:"size:13" json:"city_id"`
+	City          string `gorm:"size:100" json:"city"`
+	ProvinceID    string `gorm:"size:13" json:"province_id"`
+	Province      string `gorm:"size:100" json:"province"`
+	PostalCode    string `gorm:"size:10" json:"postal_code"`
+	Notes         string `gorm:"size:255" json:"notes"`
+}
+
+// UserAddress adalah satu alamat di buku alamat customer. Setiap customer yang
+// punya alamat memiliki tepat satu alamat default.
+type UserAddress struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	UserID    int    `gorm:"index;not null" json:"user_id"`
+	Label     string `gorm:"size:50" json:"label"` // mis. "Rumah" atau "Kantor"
+	Address   `gorm:"embedded"`
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (UserAddress) Setup(db *gorm.DB) {
+	db.AutoMigrate(&UserAddress{})
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity menautkan user ke akun di identity provider OIDC eksternal.
+// Satu pasangan (Issuer, Subject) hanya bisa milik satu user.
+type UserIdentity struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      int       `gorm:"index;not null" json:"user_id"`
+	Issuer      string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject" json:"issuer"`
+	Subject     string    `gorm:"size:255;not null;uniqueIndex:idx_user_identities_subject" json:"subject"`
+	Email       string    `json:"email"`
+	LastLoginAt time.Time `json:"last_login_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (UserIdentity) Setup(db *gorm.DB) {
+	db.AutoMigrate(&UserIdentity{})
+}
@@ -0,0 +1,207 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raihan1405/go-restapi/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8080/api/auth/oidc/callback"
+
+func newTestClient(provider *oidctest.Provider) *Client {
+	return New(Config{
+		Issuer:       provider.Issuer() + "/",
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  redirectURL,
+	}, provider.HTTPClient())
+}
+
+// login menjalankan alur lengkap seperti OIDCLogin lalu OIDCCallback
+func login(t *testing.T, client *Client, provider *oidctest.Provider) (*IDTokenClaims, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	state, _ := NewState()
+	nonce, _ := NewState()
+	verifier, err := NewPKCEVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, PKCEChallenge(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, returnedState, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if returnedState != state {
+		t.Fatalf("state = %q, want %q", returnedState, state)
+	}
+	return client.Exchange(ctx, code, verifier, nonce)
+}
+
+var testUser = oidctest.User{Subject: "user-1", Email: "budi@example.com", EmailVerified: true, Name: "Budi"}
+
+func TestDiscoveryAndAuthCodeURL(t *testing.T) {
+	provider := oidctest.NewProvider()
+	defer provider.Close()
+	client := newTestClient(provider)
+
+	doc, err := client.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if doc.TokenEndpoint != provider.Issuer()+"/token" || doc.JWKSURI != provider.Issuer()+"/jwks" {
+		t.Errorf("unexpected discovery document: %+v", doc)
+	}
+
+	authURL, err := client.AuthCodeURL(context.Background(), "state-1", "nonce-1", PKCEChallenge("verifier"))
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	parsed, _ := url.Parse(authURL)
+	query := parsed.Query()
+	if !strings.HasPrefix(authURL, doc.AuthorizationEndpoint+"?") {
+		t.Errorf("AuthCodeURL %q does not use the authorization endpoint", authURL)
+	}
+	for key, want := range map[string]string{
+		"client_id":             oidctest.ClientID,
+		"redirect_uri":          redirectURL,
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge":        PKCEChallenge("verifier"),
+		"code_challenge_method": "S256",
+		"scope":                 "openid email profile",
+	} {
+		if got := query.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	// Dokumen discovery di-cache
+	client.Discover(context.Background())
+	if provider.DiscoveryRequests != 1 {
+		t.Errorf("discovery fetched %d times, want 1", provider.DiscoveryRequests)
+	}
+}
+
+func TestDiscoveryRejectsIssuerMismatch(t *testing.T) {
+	provider := oidctest.NewProvider()
+	defer provider.Close()
+
+	// Issuer dikonfigurasi dengan host lain yang kebetulan melayani dokumen provider
+	client := New(Config{Issuer: strings.Replace(provider.Issuer(), "127.0.0.1", "localhost", 1), ClientID: oidctest.ClientID}, provider.HTTPClient())
+	if _, err := client.Discover(context.Background()); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("Discover error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	provider := oidctest.NewProvider()
+	defer provider.Close()
+	client := newTestClient(provider)
+	provider.SetUser(testUser)
+
+	claims, err := login(t, client, provider)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != testUser.Subject || claims.Email != testUser.Email || !claims.EmailVerified || claims.Name != testUser.Name {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestExchangeForwardsPKCEVerifier(t *testing.T) {
+	provider := oidctest.NewProvider()
+	defer provider.Close()
+	client := newTestClient(provider)
+	provider.SetUser(testUser)
+
+	nonce := "nonce-1"
+	authURL, err := client.AuthCodeURL(context.Background(), "state", nonce, PKCEChallenge("the-real-verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verifier yang salah ditolak provider; code tidak bisa dipakai lagi
+	if _, err := client.Exchange(context.Background(), code, "another-verifier", nonce); err == nil || !strings.Contains(err.Error(), "PKCE") {
+		t.Fatalf("Exchange with wrong verifier error = %v, want PKCE failure", err)
+	}
+
+	code, _, _ = provider.Authorize(authURL)
+	if _, err := client.Exchange(context.Background(), code, "the-real-verifier", nonce); err != nil {
+		t.Fatalf("Exchange with the right verifier: %v", err)
+	}
+}
+
+func TestExchangeRejectsInvalidIDToken(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		overrides oidctest.Overrides
+	}{
+		{"nonce mismatch", oidctest.Overrides{Nonce: "replayed-nonce"}},
+		{"issuer mismatch", oidctest.Overrides{Issuer: "https://evil.example.com"}},
+		{"audience mismatch", oidctest.Overrides{Audience: "another-client"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := oidctest.NewProvider()
+			defer provider.Close()
+			client := newTestClient(provider)
+			provider.SetUser(testUser)
+			provider.Override(tc.overrides)
+
+			if _, err := login(t, client, provider); !errors.Is(err, ErrInvalidIDToken) {
+				t.Fatalf("Exchange error = %v, want ErrInvalidIDToken", err)
+			}
+		})
+	}
+}
+
+func TestJWKSKeyRotation(t *testing.T) {
+	provider := oidctest.NewProvider()
+	defer provider.Close()
+	client := newTestClient(provider)
+	provider.SetUser(testUser)
+
+	if _, err := login(t, client, provider); err != nil {
+		t.Fatalf("login before rotation: %v", err)
+	}
+	if _, err := login(t, client, provider); err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if provider.JWKSRequests != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1 (cached)", provider.JWKSRequests)
+	}
+
+	// Provider merotasi kunci dan langsung memakai kid baru. Dalam interval
+	// refresh kid yang tidak dikenal ditolak tanpa mengambil JWKS lagi.
+	provider.RotateKey(false)
+	if _, err := login(t, client, provider); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("login with unknown kid inside refresh interval error = %v, want ErrInvalidIDToken", err)
+	}
+	if provider.JWKSRequests != 1 {
+		t.Fatalf("JWKS fetched %d times inside refresh interval, want 1", provider.JWKSRequests)
+	}
+
+	// Setelah interval lewat, kid baru memicu pengambilan ulang JWKS
+	client.mu.Lock()
+	client.keysAt = time.Now().Add(-2 * jwksRefreshInterval)
+	client.mu.Unlock()
+	if _, err := login(t, client, provider); err != nil {
+		t.Fatalf("login after rotation: %v", err)
+	}
+	if provider.JWKSRequests != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", provider.JWKSRequests)
+	}
+}
@@ -0,0 +1,284 @@
+// Package oidctest adalah identity provider OpenID Connect tiruan untuk
+// pengujian: discovery, halaman authorize tanpa UI, token endpoint yang memeriksa
+// PKCE, dan JWKS dengan kunci RSA yang bisa dirotasi. Seperti httptest, paket
+// ini hanya dipakai oleh test.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ClientID dan ClientSecret adalah kredensial yang diterima provider
+const (
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+)
+
+// User adalah akun di provider yang "login" saat Authorize dipanggil
+type User struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Overrides memalsukan isi ID token berikutnya untuk menguji penolakan token
+type Overrides struct {
+	Issuer   string
+	Audience string
+	Nonce    string
+}
+
+// grant adalah authorization code yang belum ditukar
+type grant struct {
+	user        User
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// Provider adalah identity provider tiruan di atas httptest.Server
+type Provider struct {
+	Server *httptest.Server
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PrivateKey
+	activeKID string
+	nextKID   int
+	grants    map[string]grant
+	user      User
+	overrides Overrides
+
+	// Jumlah request per endpoint, untuk memeriksa cache di client
+	DiscoveryRequests int
+	JWKSRequests      int
+	TokenRequests     int
+}
+
+// NewProvider menjalankan provider dengan satu kunci penandatangan
+func NewProvider() *Provider {
+	p := &Provider{keys: map[string]*rsa.PrivateKey{}, grants: map[string]grant{}}
+	p.RotateKey(false)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// Close mematikan server provider
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Issuer adalah URL issuer provider
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// HTTPClient adalah client yang dipakai relying party untuk menghubungi provider
+func (p *Provider) HTTPClient() *http.Client {
+	return p.Server.Client()
+}
+
+// SetUser menentukan akun yang login pada Authorize berikutnya
+func (p *Provider) SetUser(user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = user
+}
+
+// Override memalsukan claim ID token berikutnya; Overrides{} mengembalikan normal
+func (p *Provider) Override(overrides Overrides) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.overrides = overrides
+}
+
+// RotateKey membuat kunci baru dengan kid baru lalu memakainya untuk ID token
+// berikutnya. Jika keepOld false, kunci lama dihapus dari JWKS.
+func (p *Provider) RotateKey(keepOld bool) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !keepOld {
+		p.keys = map[string]*rsa.PrivateKey{}
+	}
+	p.nextKID++
+	p.activeKID = "key-" + strconv.Itoa(p.nextKID)
+	p.keys[p.activeKID] = key
+	return p.activeKID
+}
+
+// Authorize meniru user yang login di halaman authorize: parameter URL dari
+// AuthCodeURL diperiksa lalu authorization code dikembalikan bersama state
+func (p *Provider) Authorize(authURL string) (code string, state string, err error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+	switch {
+	case query.Get("response_type") != "code":
+		return "", "", errors.New("response_type must be code")
+	case query.Get("client_id") != ClientID:
+		return "", "", fmt.Errorf("unknown client_id %q", query.Get("client_id"))
+	case query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "":
+		return "", "", errors.New("PKCE S256 code_challenge is required")
+	}
+
+	code = base64.RawURLEncoding.EncodeToString(randomBytes(16))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.grants[code] = grant{
+		user:        p.user,
+		nonce:       query.Get("nonce"),
+		challenge:   query.Get("code_challenge"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	return code, query.Get("state"), nil
+}
+
+func randomBytes(n int) []byte {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return buf
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.DiscoveryRequests++
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.JWKSRequests++
+
+	keys := []map[string]string{}
+	for kid, key := range p.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+func tokenError(w http.ResponseWriter, code string, description string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": description})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request", err.Error())
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.TokenRequests++
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != ClientID || secret != ClientSecret {
+		tokenError(w, "invalid_client", "client authentication failed")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, "unsupported_grant_type", "only authorization_code is supported")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	g, ok := p.grants[code]
+	if !ok {
+		tokenError(w, "invalid_grant", "unknown or used authorization code")
+		return
+	}
+	// Code hanya bisa ditukar satu kali
+	delete(p.grants, code)
+
+	if r.PostForm.Get("redirect_uri") != g.redirectURI {
+		tokenError(w, "invalid_grant", "redirect_uri does not match the authorization request")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, "invalid_grant", "PKCE verification failed")
+		return
+	}
+
+	issuer, audience, nonce := p.Issuer(), ClientID, g.nonce
+	if p.overrides.Issuer != "" {
+		issuer = p.overrides.Issuer
+	}
+	if p.overrides.Audience != "" {
+		audience = p.overrides.Audience
+	}
+	if p.overrides.Nonce != "" {
+		nonce = p.overrides.Nonce
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                issuer,
+		"sub":                g.user.Subject,
+		"aud":                audience,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"email":              g.user.Email,
+		"email_verified":     g.user.EmailVerified,
+		"name":               g.user.Name,
+		"preferred_username": g.user.PreferredUsername,
+	})
+	token.Header["kid"] = p.activeKID
+	idToken, err := token.SignedString(p.keys[p.activeKID])
+	if err != nil {
+		tokenError(w, "server_error", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"id_token": idToken, "access_token": "mock-access-token", "token_type": "Bearer"})
+}
@@ -0,0 +1,233 @@
+// Package regions mengimpor daftar wilayah administratif Indonesia (provinsi,
+// kabupaten/kota, kecamatan, kelurahan/desa) ke database dan memvalidasi alamat
+// terhadapnya.
+//
+// Sumber data adalah CSV dengan kolom code,name,postal_code memakai kode
+// Kemendagri bertitik. Tingkat wilayah ditentukan oleh jumlah segmen kode dan
+// induknya adalah kode tanpa segmen terakhir. Repo hanya membawa contoh kecil;
+// daftar lengkap dipasang lewat REGIONS_DATA_FILE. Di luar APP_ENV=development
+// (atau test) contoh itu ditolak agar server tidak berjalan dengan data wilayah
+// yang tidak lengkap.
+package regions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DataFileEnv menunjuk file CSV daftar wilayah lengkap
+const DataFileEnv = "REGIONS_DATA_FILE"
+
+// checksumSetting menyimpan checksum data yang terakhir diimpor sehingga import
+// hanya berjalan saat datanya berubah
+const checksumSetting = "regions.checksum"
+
+const batchSize = 1000
+
+//go:embed data/regions.csv
+var sampleData []byte
+
+var (
+	ErrSampleData     = errors.New(DataFileEnv + " is not set and the bundled sample only covers a few villages; set it to the full Kemendagri CSV or run with " + models.EnvironmentEnv + "=development")
+	ErrUnknownVillage = errors.New("unknown village")
+	ErrRegionMismatch = errors.New("region does not belong to the selected village")
+)
+
+var (
+	codePattern       = regexp.MustCompile(`^\d{2}(\.\d{2}(\.\d{2}(\.\d{4})?)?)?$`)
+	postalCodePattern = regexp.MustCompile(`^\d{5}$`)
+)
+
+// Dataset adalah isi file wilayah yang sudah diurai
+type Dataset struct {
+	Provinces []models.Province
+	Cities    []models.City
+	Districts []models.District
+	Villages  []models.Village
+}
+
+// Parse membaca CSV wilayah. Setiap kode harus unik dan induknya harus muncul
+// lebih dulu di file.
+func Parse(r io.Reader) (*Dataset, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	dataset := &Dataset{}
+	seen := map[string]bool{}
+	line := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "code") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected code,name[,postal_code]", line)
+		}
+
+		code := strings.TrimSpace(record[0])
+		name := strings.TrimSpace(record[1])
+		postalCode := ""
+		if len(record) > 2 {
+			postalCode = strings.TrimSpace(record[2])
+		}
+
+		if !codePattern.MatchString(code) {
+			return nil, fmt.Errorf("line %d: invalid region code %q", line, code)
+		}
+		if name == "" {
+			return nil, fmt.Errorf("line %d: region %s has no name", line, code)
+		}
+		if seen[code] {
+			return nil, fmt.Errorf("line %d: duplicate region code %s", line, code)
+		}
+
+		segments := strings.Split(code, ".")
+		parent := strings.Join(segments[:len(segments)-1], ".")
+		if len(segments) > 1 && !seen[parent] {
+			return nil, fmt.Errorf("line %d: region %s appears before its parent %s", line, code, parent)
+		}
+		if postalCode != "" && (len(segments) != 4 || !postalCodePattern.MatchString(postalCode)) {
+			return nil, fmt.Errorf("line %d: invalid postal code %q for %s", line, postalCode, code)
+		}
+		seen[code] = true
+
+		switch len(segments) {
+		case 1:
+			dataset.Provinces = append(dataset.Provinces, models.Province{ID: code, Name: name})
+		case 2:
+			dataset.Cities = append(dataset.Cities, models.City{ID: code, ProvinceID: parent, Name: name})
+		case 3:
+			dataset.Districts = append(dataset.Districts, models.District{ID: code, CityID: parent, Name: name})
+		case 4:
+			dataset.Villages = append(dataset.Villages, models.Village{ID: code, DistrictID: parent, Name: name, PostalCode: postalCode})
+		}
+	}
+
+	if len(dataset.Provinces) == 0 {
+		return nil, errors.New("region data contains no provinces")
+	}
+	return dataset, nil
+}
+
+// Import menyimpan dataset dalam satu transaksi. Wilayah yang sudah ada
+// diperbarui; wilayah yang tidak ada lagi di file dibiarkan karena mungkin masih
+// dirujuk alamat lama.
+func Import(db *gorm.DB, dataset *Dataset) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Induk disimpan lebih dulu daripada anaknya
+		for _, rows := range []interface{}{dataset.Provinces, dataset.Cities, dataset.Districts, dataset.Villages} {
+			if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(rows, batchSize).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// source mengembalikan isi file REGIONS_DATA_FILE atau contoh bawaan beserta namanya
+func source() ([]byte, string, error) {
+	path := os.Getenv(DataFileEnv)
+	if path == "" {
+		if !models.Development() {
+			return nil, "", ErrSampleData
+		}
+		return sampleData, "bundled sample", nil
+	}
+	data, err := os.ReadFile(path)
+	return data, path, err
+}
+
+// Sync mengimpor data wilayah saat startup jika isinya berbeda dari import terakhir
+func Sync(db *gorm.DB) error {
+	data, name, err := source()
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	var current models.Setting
+	if err := db.Where("`key` = ?", checksumSetting).Limit(1).Find(&current).Error; err != nil {
+		return err
+	}
+	if current.Value == checksum {
+		return nil
+	}
+
+	dataset, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := Import(db, dataset); err != nil {
+		return err
+	}
+
+	log.Printf("Imported %d provinces, %d cities, %d districts and %d villages from %s\n",
+		len(dataset.Provinces), len(dataset.Cities), len(dataset.Districts), len(dataset.Villages), name)
+	return models.SetSetting(db, checksumSetting, checksum)
+}
+
+// Path adalah kelurahan beserta seluruh wilayah induknya
+type Path struct {
+	Province models.Province
+	City     models.City
+	District models.District
+	Village  models.Village
+}
+
+// Resolve mencari kelurahan dan menelusuri induknya sampai provinsi
+func Resolve(db *gorm.DB, villageID string) (*Path, error) {
+	var path Path
+	err := db.Where("id = ?", villageID).First(&path.Village).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUnknownVillage
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("id = ?", path.Village.DistrictID).First(&path.District).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("id = ?", path.District.CityID).First(&path.City).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("id = ?", path.City.ProvinceID).First(&path.Province).Error; err != nil {
+		return nil, err
+	}
+	return &path, nil
+}
+
+// Check memastikan kode provinsi, kota dan kecamatan yang dikirim klien (boleh
+// kosong) memang induk kelurahan tersebut
+func (p *Path) Check(provinceID string, cityID string, districtID string) error {
+	if (provinceID != "" && provinceID != p.Province.ID) ||
+		(cityID != "" && cityID != p.City.ID) ||
+		(districtID != "" && districtID != p.District.ID) {
+		return ErrRegionMismatch
+	}
+	return nil
+}
@@ -1,76 +1,171 @@
 package routes
 
 import (
-	"log"
-	"os"
-
 	"github.com/gofiber/fiber/v2"
-	jwtware "github.com/gofiber/jwt/v3"
+	"github.com/raihan1405/go-restapi/auth"
 	"github.com/raihan1405/go-restapi/controllers"
+	"github.com/raihan1405/go-restapi/models"
 )
 
 func Setup(app *fiber.App) {
 
+	app.Get("/.well-known/jwks.json", controllers.GetJWKS)
 	app.Post("/api/register", controllers.Register)
 	app.Post("/api/login", controllers.Login)
+	app.Get("/api/verify-email", controllers.VerifyEmail)
+	app.Post("/api/password/forgot", controllers.ForgotPassword)
+	app.Post("/api/password/reset", controllers.ResetPassword)
+	app.Get("/api/auth/oidc/login", controllers.OIDCLogin)
+	app.Get("/api/auth/oidc/callback", controllers.OIDCCallback)
+
+	// Data wilayah bersifat publik agar form alamat bisa dipakai sebelum login
+	app.Get("/api/regions/provinces", controllers.ListProvinces)
+	app.Get("/api/regions/provinces/:id/cities", controllers.ListCities)
+	app.Get("/api/regions/cities/:id/districts", controllers.ListDistricts)
+	app.Get("/api/regions/districts/:id/villages", controllers.ListVillages)
 	app.Post("/admin/login", controllers.LoginAdmin)
 	app.Post("/operator/loginOperator", controllers.LoginOperator)
+	app.Post("/operator/login/mfa", controllers.CompleteMFALogin(auth.RoleOperator))
+	app.Post("/admin/login/mfa", controllers.CompleteMFALogin(auth.RoleAdmin))
+
+	// Refresh endpoints sit outside the guarded groups because the access token may already be expired
+	// Refresh lewat cookie tetap memeriksa token CSRF di dalam auth.RotateSession
+	app.Post("/api/token/refresh", controllers.RefreshUserToken)
+	app.Post("/operator/token/refresh", controllers.RefreshOperatorToken)
+	app.Post("/admin/token/refresh", controllers.RefreshAdminToken)
 	
-	api := app.Group("/api", jwtware.New(jwtware.Config{
-		SigningKey:  []byte(os.Getenv("JWT_SECRET")), 
-		TokenLookup: "cookie:jwt",
-	}))
+	api := app.Group("/api", auth.RequireRole(auth.RoleUser))
 
 	api.Get("/userProducts", controllers.GetAllProducts)
 	api.Get("/user", controllers.GetUser)
+	api.Post("/verify-email/resend", controllers.ResendVerificationEmail)
 	api.Post("/logoutUser", controllers.LogoutUser)
 	api.Put("/user", controllers.UpdateProfile)
 	api.Put("/user/password", controllers.UpdatePassword)
+	api.Get("/user/export", controllers.ExportAccount)
+	api.Delete("/user", controllers.DeleteAccount)
 	api.Get("/Products", controllers.GetAllProducts)
+	api.Get("/products/search", controllers.SearchProducts)
+	api.Get("/products/suggest", controllers.SuggestProducts)
+	api.Get("/categories", controllers.ListCategories)
 	api.Post("/addToCart", controllers.AddToCart)
 	api.Get("/itemCart", controllers.GetCart)
 	api.Put("/itemCart/edit/:id", controllers.UpdateCartItem)
 	api.Delete("deleteCart/:id", controllers.RemoveFromCart)
-	api.Post("/createInvoice", controllers.CreateInvoice)
+	api.Post("/createInvoice", controllers.RequireVerifiedEmail, controllers.CreateInvoice)
 	api.Get("/getInvoice", controllers.GetAllInvoices)
+	api.Put("/invoices/cancel/:id", controllers.CancelInvoice)
+	api.Get("/addresses", controllers.ListAddresses)
+	api.Post("/addresses", controllers.CreateAddress)
+	api.Get("/addresses/:id", controllers.GetAddress)
+	api.Put("/addresses/:id", controllers.UpdateAddress)
+	api.Put("/addresses/:id/default", controllers.SetDefaultAddress)
+	api.Delete("/addresses/:id", controllers.DeleteAddress)
+	api.Get("/sessions", controllers.ListSessions)
+	api.Delete("/sessions", controllers.RevokeOtherSessions)
+	api.Delete("/sessions/:id", controllers.RevokeSession)
 	
 
-	apiOperator := app.Group("/operator", jwtware.New(jwtware.Config{
-		SigningKey:   []byte(os.Getenv("JWT_SECRET_OPERATOR")),
-		TokenLookup:  "cookie:jwt_operator",                    
-		ErrorHandler: jwtError,
-	}))
+	// Integrasi mesin memakai X-API-Key; key hanya lolos route yang memerlukan permission sesuai scope-nya
+	apiOperator := app.Group("/operator", auth.AcceptAPIKeys, auth.RequireRole(auth.RoleOperator))
 
-	apiOperator.Get("/Products", controllers.GetAllProducts)
-	apiOperator.Get("/dashboard", controllers.OperatorDashboard)
-	apiOperator.Post("/products", controllers.AddProduct)
+	// Routes still reachable while the operator must change the initial password
+	apiOperator.Put("/password", controllers.ChangeOperatorPassword)
 	apiOperator.Post("/logoutOperator", controllers.LogoutOperator)
-	apiOperator.Put("/products/edit/:id", controllers.EditProduct)
-	apiOperator.Get("/getAllInvoice", controllers.GetAllInvoicesForOperator)
-	apiOperator.Put("/invoices/approve", controllers.ApproveInvoices)
-	apiOperator.Put("/invoices/reject", controllers.RejectInvoices)
-	apiOperator.Get("/invoices/accepted", controllers.GetAcceptInvoice)
-	apiOperator.Put("/invoices/updateShipment", controllers.UpdateStatusInvoice)
+	apiOperator.Get("/sessions", controllers.ListSessions)
+	apiOperator.Delete("/sessions", controllers.RevokeOtherSessions)
+	apiOperator.Delete("/sessions/:id", controllers.RevokeSession)
+	apiOperator.Use(controllers.PasswordChangeGuard(auth.RoleOperator))
+
+	// MFA enrollment stays reachable while MFA is mandatory but not yet enabled
+	apiOperator.Post("/mfa/enroll", controllers.EnrollMFA(auth.RoleOperator))
+	apiOperator.Post("/mfa/verify", controllers.ConfirmMFA(auth.RoleOperator))
+	apiOperator.Delete("/mfa", controllers.DisableMFA(auth.RoleOperator))
+	apiOperator.Post("/mfa/recovery-codes", controllers.RegenerateRecoveryCodes(auth.RoleOperator))
+	apiOperator.Use(controllers.MFAEnrollmentGuard(auth.RoleOperator))
 
+	// Setiap route staff mendeklarasikan permission yang dibutuhkan; lihat models.PermissionCatalog
+	apiOperator.Get("/Products", auth.RequirePermission(models.PermissionProductsView), controllers.GetAllProducts)
+	apiOperator.Get("/dashboard", auth.RequirePermission(models.PermissionDashboardView), controllers.OperatorDashboard)
+	apiOperator.Post("/products", auth.RequirePermission(models.PermissionProductsEdit), controllers.AddProduct)
+	apiOperator.Put("/products/edit/:id", auth.RequirePermission(models.PermissionProductsEdit), controllers.EditProduct)
+	apiOperator.Get("/getAllInvoice", auth.RequirePermission(models.PermissionInvoicesView), controllers.GetAllInvoicesForOperator)
+	apiOperator.Put("/invoices/approve", auth.RequirePermission(models.PermissionInvoicesApprove), controllers.ApproveInvoices)
+	apiOperator.Put("/invoices/reject", auth.RequirePermission(models.PermissionInvoicesApprove), controllers.RejectInvoices)
+	apiOperator.Get("/invoices/accepted", auth.RequirePermission(models.PermissionInvoicesView), controllers.GetAcceptInvoice)
+	apiOperator.Put("/invoices/updateShipment", auth.RequirePermission(models.PermissionInvoicesShip), controllers.UpdateStatusInvoice)
 
-	apiAdmin := app.Group("/admin", jwtware.New(jwtware.Config{
-		SigningKey:   []byte(os.Getenv("JWT_SECRET_ADMIN")),
-		TokenLookup:  "cookie:jwt_admin",                    
-		ErrorHandler: jwtError,
-	}))
 
-	apiAdmin.Get("/adminProducts", controllers.GetAllProducts)
+	apiAdmin := app.Group("/admin", auth.RequireRole(auth.RoleAdmin))
+
+	// Routes still reachable while the admin must change the initial password
+	apiAdmin.Put("/password", controllers.ChangeAdminPassword)
 	apiAdmin.Post("/logoutAdmin", controllers.LogoutAdmin)
-	apiAdmin.Get("/productAdmin", controllers.GetAllProducts)
-	apiAdmin.Get("/getAllInvoiceAdmin", controllers.GetAllInvoicesForAdmin)
-	apiAdmin.Get("/getProductReport/:id", controllers.GenerateProductReport)
+	apiAdmin.Get("/sessions", controllers.ListSessions)
+	apiAdmin.Delete("/sessions", controllers.RevokeOtherSessions)
+	apiAdmin.Delete("/sessions/:id", controllers.RevokeSession)
+	apiAdmin.Use(controllers.PasswordChangeGuard(auth.RoleAdmin))
 
-}
+	// MFA enrollment stays reachable while MFA is mandatory but not yet enabled
+	apiAdmin.Post("/mfa/enroll", controllers.EnrollMFA(auth.RoleAdmin))
+	apiAdmin.Post("/mfa/verify", controllers.ConfirmMFA(auth.RoleAdmin))
+	apiAdmin.Delete("/mfa", controllers.DisableMFA(auth.RoleAdmin))
+	apiAdmin.Post("/mfa/recovery-codes", controllers.RegenerateRecoveryCodes(auth.RoleAdmin))
+	apiAdmin.Use(controllers.MFAEnrollmentGuard(auth.RoleAdmin))
+
+	apiAdmin.Get("/adminProducts", auth.RequirePermission(models.PermissionProductsView), controllers.GetAllProducts)
+	apiAdmin.Get("/productAdmin", auth.RequirePermission(models.PermissionProductsView), controllers.GetAllProducts)
+	apiAdmin.Get("/getAllInvoiceAdmin", auth.RequirePermission(models.PermissionInvoicesView), controllers.GetAllInvoicesForAdmin)
+	apiAdmin.Post("/search/rebuild", auth.RequirePermission(models.PermissionProductsEdit), controllers.RebuildSearchIndex)
+	apiAdmin.Get("/getProductReport/:id", auth.RequirePermission(models.PermissionReportsView), controllers.GenerateProductReport)
+	apiAdmin.Post("/sessions/revoke-all", auth.RequirePermission(models.PermissionSessionsManage), controllers.AdminRevokeAccountSessions)
+	apiAdmin.Get("/audit-logs", auth.RequirePermission(models.PermissionAuditView), controllers.GetAuditLogs)
+	apiAdmin.Post("/logins/unlock", auth.RequirePermission(models.PermissionLoginsUnlock), controllers.UnlockLogin)
+
+	staff := auth.RequirePermission(models.PermissionStaffManage)
+	apiAdmin.Post("/operators", staff, controllers.CreateOperator)
+	apiAdmin.Get("/operators", staff, controllers.ListOperators)
+	apiAdmin.Put("/operators/:id", staff, controllers.UpdateOperator)
+	apiAdmin.Put("/operators/:id/deactivate", staff, controllers.DeactivateOperator)
+	apiAdmin.Put("/operators/:id/reactivate", staff, controllers.ReactivateOperator)
+	apiAdmin.Post("/admins", staff, controllers.CreateAdmin)
+	apiAdmin.Get("/admins", staff, controllers.ListAdmins)
+	apiAdmin.Put("/admins/:id", staff, controllers.UpdateAdmin)
+	apiAdmin.Put("/admins/:id/deactivate", staff, controllers.DeactivateAdmin)
+	apiAdmin.Put("/admins/:id/reactivate", staff, controllers.ReactivateAdmin)
+	apiAdmin.Put("/operators/:id/mfa/reset", staff, controllers.ResetStaffMFA(auth.RoleOperator))
+	apiAdmin.Put("/admins/:id/mfa/reset", staff, controllers.ResetStaffMFA(auth.RoleAdmin))
+
+	roles := auth.RequirePermission(models.PermissionRolesManage)
+	apiAdmin.Get("/permissions", roles, controllers.ListPermissions)
+	apiAdmin.Get("/roles", roles, controllers.ListRoles)
+	apiAdmin.Post("/roles", roles, controllers.CreateRole)
+	apiAdmin.Put("/roles/:id", roles, controllers.UpdateRole)
+	apiAdmin.Delete("/roles/:id", roles, controllers.DeleteRole)
+	apiAdmin.Put("/operators/:id/roles", roles, controllers.AssignOperatorRoles)
+	apiAdmin.Put("/admins/:id/roles", roles, controllers.AssignAdminRoles)
+
+	apiAdmin.Get("/settings/mfa", auth.RequirePermission(models.PermissionSettingsManage), controllers.GetMFASetting)
+	apiAdmin.Put("/settings/mfa", auth.RequirePermission(models.PermissionSettingsManage), controllers.UpdateMFASetting)
+	apiAdmin.Post("/signing-keys/rotate", auth.RequirePermission(models.PermissionSettingsManage), controllers.RotateSigningKey)
+
+	categories := auth.RequirePermission(models.PermissionCategoriesManage)
+	apiAdmin.Get("/categories", categories, controllers.ListAllCategories)
+	apiAdmin.Post("/categories", categories, controllers.CreateCategory)
+	apiAdmin.Put("/categories/:id", categories, controllers.UpdateCategory)
+	apiAdmin.Delete("/categories/:id", categories, controllers.DeleteCategory)
+
+	apiAdmin.Get("/users", auth.RequirePermission(models.PermissionUsersView), controllers.ListUsers)
+	apiAdmin.Get("/users/:id", auth.RequirePermission(models.PermissionUsersView), controllers.GetUserDetail)
+
+	users := auth.RequirePermission(models.PermissionUsersManage)
+	apiAdmin.Put("/users/:id", users, controllers.UpdateUser)
+	apiAdmin.Put("/users/:id/suspend", users, controllers.SuspendUser)
+	apiAdmin.Put("/users/:id/reinstate", users, controllers.ReinstateUser)
+
+	apiKeys := auth.RequirePermission(models.PermissionAPIKeysManage)
+	apiAdmin.Post("/api-keys", apiKeys, controllers.CreateAPIKey)
+	apiAdmin.Get("/api-keys", apiKeys, controllers.ListAPIKeys)
+	apiAdmin.Delete("/api-keys/:id", apiKeys, controllers.RevokeAPIKey)
 
-func jwtError(c *fiber.Ctx, err error) error {
-	log.Printf("JWT Error: %v\n", err)
-	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-		"message": "unauthenticated",
-		"error":   err.Error(),
-	})
 }
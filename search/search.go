@@ -0,0 +1,414 @@
+// Package search adalah index pencarian produk di memori. Nama produk, merek dan
+// kategori dipecah menjadi kata, di-stem dalam bahasa Indonesia dan Inggris, lalu
+// dicari dengan toleransi salah ketik dan pencocokan awalan untuk kata terakhir
+// (pencarian sambil mengetik). Hasil diurutkan menurut relevansi dan popularitas
+// penjualan dari InvoiceItem.
+//
+// Index dibangun dari database saat startup dan dibangun ulang secara berkala;
+// perubahan produk dari handler diterapkan langsung lewat Put.
+package search
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raihan1405/go-restapi/models"
+	"gorm.io/gorm"
+)
+
+// rebuildInterval adalah jarak antar rebuild otomatis untuk memperbarui popularitas
+const rebuildInterval = time.Hour
+
+// Bobot field: kecocokan di nama produk lebih berarti daripada di merek atau kategori
+const (
+	nameWeight     = 3.0
+	brandWeight    = 2.0
+	categoryWeight = 1.0
+)
+
+// Faktor skor untuk kecocokan yang tidak persis
+const (
+	prefixFactor = 0.8
+	typoPenalty  = 0.3
+)
+
+// popularityWeight adalah tambahan skor maksimum (relatif) untuk produk terlaris
+const popularityWeight = 0.5
+
+// Hit adalah satu produk hasil pencarian
+type Hit struct {
+	ProductID int
+	Score     float64
+}
+
+// Stats menjelaskan isi index setelah dibangun
+type Stats struct {
+	Products int       `json:"products"`
+	Terms    int       `json:"terms"`
+	BuiltAt  time.Time `json:"built_at"`
+}
+
+// document adalah produk yang sudah dianalisis
+type document struct {
+	words []string           // kata asli untuk saran autocomplete
+	terms map[string]float64 // kata dan kata dasar beserta bobot field tertingginya
+}
+
+// Index aman dipakai bersamaan dari banyak goroutine
+type Index struct {
+	mu            sync.RWMutex
+	docs          map[int]*document
+	postings      map[string]map[int]float64
+	popularity    map[int]int
+	maxPopularity int
+	builtAt       time.Time
+
+	// pending mencatat Put selama rebuild berjalan agar tidak tertimpa hasil rebuild
+	pending map[int]models.Product
+}
+
+// New membuat index kosong
+func New() *Index {
+	return &Index{
+		docs:       map[int]*document{},
+		postings:   map[string]map[int]float64{},
+		popularity: map[int]int{},
+	}
+}
+
+func analyze(product *models.Product) *document {
+	doc := &document{terms: map[string]float64{}}
+	seen := map[string]bool{}
+	for _, field := range []struct {
+		text   string
+		weight float64
+	}{
+		{product.ProductName, nameWeight},
+		{product.BrandName, brandWeight},
+		{product.CategoryName(), categoryWeight},
+	} {
+		for _, word := range tokenize(field.text) {
+			if !seen[word] {
+				seen[word] = true
+				doc.words = append(doc.words, word)
+			}
+			for _, term := range variants(word) {
+				doc.terms[term] = math.Max(doc.terms[term], field.weight)
+			}
+		}
+	}
+	return doc
+}
+
+// put dan remove harus dipanggil dengan mu terkunci
+func (ix *Index) put(product *models.Product) {
+	ix.remove(product.ID)
+	doc := analyze(product)
+	ix.docs[product.ID] = doc
+	for term, weight := range doc.terms {
+		if ix.postings[term] == nil {
+			ix.postings[term] = map[int]float64{}
+		}
+		ix.postings[term][product.ID] = weight
+	}
+}
+
+func (ix *Index) remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range doc.terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+// Put menambahkan atau memperbarui satu produk
+func (ix *Index) Put(product models.Product) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.put(&product)
+	if ix.pending != nil {
+		ix.pending[product.ID] = product
+	}
+}
+
+// Load mengganti seluruh isi index dengan produk dan jumlah terjual yang diberikan
+func (ix *Index) Load(products []models.Product, popularity map[int]int) Stats {
+	fresh := New()
+	for i := range products {
+		fresh.put(&products[i])
+	}
+	for _, sold := range popularity {
+		fresh.maxPopularity = max(fresh.maxPopularity, sold)
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.docs, ix.postings = fresh.docs, fresh.postings
+	ix.popularity, ix.maxPopularity = popularity, fresh.maxPopularity
+	ix.builtAt = time.Now()
+	for _, product := range ix.pending {
+		ix.put(&product)
+	}
+	ix.pending = nil
+	return ix.stats()
+}
+
+func (ix *Index) stats() Stats {
+	return Stats{Products: len(ix.docs), Terms: len(ix.postings), BuiltAt: ix.builtAt}
+}
+
+// Stats mengembalikan ukuran index saat ini
+func (ix *Index) Stats() Stats {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.stats()
+}
+
+// expand mencari term di index yang cocok dengan satu kata query beserta
+// faktornya: persis atau kata dasar 1, awalan (hanya kata terakhir) dan salah
+// ketik lebih rendah
+func (ix *Index) expand(word string, prefix bool) map[string]float64 {
+	matches := map[string]float64{}
+	for _, term := range variants(word) {
+		if _, ok := ix.postings[term]; ok {
+			matches[term] = 1
+		}
+	}
+
+	limit := maxEdits(word)
+	for term := range ix.postings {
+		if _, ok := matches[term]; ok {
+			continue
+		}
+		factor := 0.0
+		if prefix && strings.HasPrefix(term, word) {
+			factor = prefixFactor
+		}
+		if limit > 0 {
+			if d := editDistance(word, term, limit); d <= limit {
+				factor = math.Max(factor, 1-typoPenalty*float64(d))
+			}
+		}
+		if factor > 0 {
+			matches[term] = factor
+		}
+	}
+	return matches
+}
+
+// match menilai setiap dokumen untuk kata-kata query dan mengembalikan skor serta
+// jumlah kata query yang cocok per dokumen
+func (ix *Index) match(words []string, prefixLast bool) (map[int]float64, map[int]int) {
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	total := float64(len(ix.docs))
+
+	for i, word := range words {
+		best := map[int]float64{}
+		for term, factor := range ix.expand(word, prefixLast && i == len(words)-1) {
+			postings := ix.postings[term]
+			idf := math.Log(1 + total/float64(len(postings)))
+			for id, weight := range postings {
+				best[id] = math.Max(best[id], weight*idf*factor)
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+			matched[id]++
+		}
+	}
+	return scores, matched
+}
+
+// boost menaikkan skor produk yang laris secara logaritmik
+func (ix *Index) boost(id int) float64 {
+	if ix.maxPopularity == 0 {
+		return 1
+	}
+	return 1 + popularityWeight*math.Log1p(float64(ix.popularity[id]))/math.Log1p(float64(ix.maxPopularity))
+}
+
+// Search mencari produk. Produk yang cocok dengan semua kata diutamakan; jika
+// tidak ada, dipakai produk dengan kata cocok terbanyak.
+func (ix *Index) Search(query string, limit int) []Hit {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	scores, matched := ix.match(words, true)
+	most := 0
+	for _, count := range matched {
+		most = max(most, count)
+	}
+
+	hits := []Hit{}
+	for id, score := range scores {
+		if matched[id] == most {
+			hits = append(hits, Hit{ProductID: id, Score: score * ix.boost(id)})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ProductID < hits[j].ProductID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// Suggest melengkapi kata terakhir query dengan kata dari produk yang cocok
+// dengan kata-kata sebelumnya, diurutkan menurut jumlah dan popularitas produknya
+func (ix *Index) Suggest(query string, limit int) []string {
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+	last, context := words[len(words)-1], words[:len(words)-1]
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	candidates := ix.docs
+	if len(context) > 0 {
+		_, matched := ix.match(context, false)
+		candidates = map[int]*document{}
+		for id, count := range matched {
+			if count == len(context) {
+				candidates[id] = ix.docs[id]
+			}
+		}
+	}
+
+	weights := map[string]float64{}
+	collect := func(accept func(word string) bool) {
+		for id, doc := range candidates {
+			for _, word := range doc.words {
+				if accept(word) {
+					weights[word] += ix.boost(id)
+				}
+			}
+		}
+	}
+	collect(func(word string) bool { return strings.HasPrefix(word, last) })
+	if len(weights) == 0 && maxEdits(last) > 0 {
+		// Tidak ada yang berawalan sama: coba kata yang mirip (salah ketik)
+		collect(func(word string) bool { return editDistance(last, word, maxEdits(last)) <= maxEdits(last) })
+	}
+
+	completions := make([]string, 0, len(weights))
+	for word := range weights {
+		completions = append(completions, word)
+	}
+	sort.Slice(completions, func(i, j int) bool {
+		if weights[completions[i]] != weights[completions[j]] {
+			return weights[completions[i]] > weights[completions[j]]
+		}
+		return completions[i] < completions[j]
+	})
+	if limit > 0 && len(completions) > limit {
+		completions = completions[:limit]
+	}
+
+	prefix := strings.Join(context, " ")
+	for i, word := range completions {
+		if prefix != "" {
+			completions[i] = prefix + " " + word
+		}
+	}
+	return completions
+}
+
+// popularityRow adalah jumlah terjual satu produk
+type popularityRow struct {
+	ProductID int
+	Sold      int
+}
+
+// Popularity menghitung jumlah terjual per produk dari invoice yang tidak
+// ditolak atau dibatalkan
+func Popularity(db *gorm.DB) (map[int]int, error) {
+	var rows []popularityRow
+	err := db.Table("invoice_items").
+		Select("invoice_items.product_id, SUM(invoice_items.quantity) AS sold").
+		Joins("JOIN invoices ON invoices.id = invoice_items.invoice_id").
+		Where("invoices.status NOT IN ?", []string{models.InvoiceStatusRejected, models.InvoiceStatusCancelled}).
+		Group("invoice_items.product_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	popularity := make(map[int]int, len(rows))
+	for _, row := range rows {
+		popularity[row.ProductID] = row.Sold
+	}
+	return popularity, nil
+}
+
+// Rebuild membaca ulang semua produk dan data penjualan dari database
+func (ix *Index) Rebuild(db *gorm.DB) (Stats, error) {
+	ix.mu.Lock()
+	ix.pending = map[int]models.Product{}
+	ix.mu.Unlock()
+
+	var products []models.Product
+	err := db.Preload("Category").Find(&products).Error
+	var popularity map[int]int
+	if err == nil {
+		popularity, err = Popularity(db)
+	}
+	if err != nil {
+		ix.mu.Lock()
+		ix.pending = nil
+		ix.mu.Unlock()
+		return Stats{}, err
+	}
+	return ix.Load(products, popularity), nil
+}
+
+// Default adalah index yang dipakai handler
+var Default = New()
+
+// rebuildMu mencegah dua rebuild Default berjalan bersamaan
+var rebuildMu sync.Mutex
+
+// Rebuild membangun ulang Default dari database
+func Rebuild(db *gorm.DB) (Stats, error) {
+	rebuildMu.Lock()
+	defer rebuildMu.Unlock()
+	return Default.Rebuild(db)
+}
+
+// Start membangun Default saat startup lalu memperbaruinya setiap jam agar
+// popularitas mengikuti penjualan terbaru
+func Start(db *gorm.DB) {
+	if stats, err := Rebuild(db); err != nil {
+		log.Printf("Cannot build search index: %v\n", err)
+	} else {
+		log.Printf("Search index built with %d products\n", stats.Products)
+	}
+	go func() {
+		for range time.Tick(rebuildInterval) {
+			if _, err := Rebuild(db); err != nil {
+				log.Printf("Cannot rebuild search index: %v\n", err)
+			}
+		}
+	}()
+}
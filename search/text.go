@@ -0,0 +1,245 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minStemLength adalah panjang minimum kata dasar bahasa Indonesia setelah
+// imbuhan dibuang; pemotongan yang menyisakan lebih sedikit dibatalkan
+// (pensil tidak menjadi sil, permen tidak menjadi men)
+const minStemLength = 4
+
+// minEnglishStemLength lebih pendek karena akhiran bahasa Inggris jarang salah
+// potong dan kata dasar tiga huruf umum di nama produk (bags -> bag)
+const minEnglishStemLength = 3
+
+// tokenize memecah teks menjadi kata huruf kecil; tanda baca dan operator dibuang
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// variants mengembalikan kata beserta kata dasarnya dalam bahasa Indonesia dan
+// Inggris. Bahasa sebuah kata tidak diketahui, jadi keduanya di-index dan dicari;
+// stemmer Indonesia hanya dipakai jika kata dan bentuk dasar Inggrisnya sama-sama
+// berejaan Indonesia (memories dianggap Inggris karena memory).
+func variants(word string) []string {
+	result := []string{word}
+	english := stemEnglish(word)
+	stems := []string{english}
+	if looksIndonesian(word) && looksIndonesian(english) {
+		stems = []string{stemIndonesian(word), english}
+	}
+	for _, stem := range stems {
+		if stem != word && (len(result) == 1 || stem != result[1]) {
+			result = append(result, stem)
+		}
+	}
+	return result
+}
+
+// trimSuffix membuang akhiran jika sisanya minimal minLength huruf
+func trimSuffix(word string, suffix string, minLength int) (string, bool) {
+	if !strings.HasSuffix(word, suffix) || utf8.RuneCountInString(word)-utf8.RuneCountInString(suffix) < minLength {
+		return word, false
+	}
+	return strings.TrimSuffix(word, suffix), true
+}
+
+func isVowel(b byte) bool {
+	return strings.IndexByte("aeiou", b) >= 0
+}
+
+// looksIndonesian menolak kata yang ejaannya tidak mungkin bahasa Indonesia
+// baku: angka atau huruf non-ASCII, huruf q/x, y yang tidak diikuti vokal
+// (memory, keyboard), c yang tidak diikuti vokal (black, chip), gugus ph/th/sh
+// dan vokal ganda ee/oo. Kata seperti itu hanya di-stem dalam bahasa Inggris.
+func looksIndonesian(word string) bool {
+	for i := 0; i < len(word); i++ {
+		b := word[i]
+		if b < 'a' || b > 'z' || b == 'q' || b == 'x' {
+			return false
+		}
+		next := byte(0)
+		if i+1 < len(word) {
+			next = word[i+1]
+		}
+		switch {
+		case (b == 'y' || b == 'c') && !isVowel(next):
+			return false
+		case next == 'h' && strings.IndexByte("pts", b) >= 0:
+			return false
+		case (b == 'e' || b == 'o') && next == b:
+			return false
+		}
+	}
+	return true
+}
+
+// stemIndonesian adalah stemmer ringan berbasis aturan (tanpa kamus) mengikuti
+// urutan Nazief-Adriani: partikel, kata ganti milik, akhiran, lalu satu awalan
+// beserta peluluhan huruf awal (menulis -> tulis, memukul -> pukul).
+func stemIndonesian(word string) string {
+	for _, suffix := range []string{"lah", "kah", "tah", "pun"} {
+		if stem, ok := trimSuffix(word, suffix, minStemLength); ok {
+			word = stem
+			break
+		}
+	}
+	for _, suffix := range []string{"nya", "ku", "mu"} {
+		if stem, ok := trimSuffix(word, suffix, minStemLength); ok {
+			word = stem
+			break
+		}
+	}
+	// Akhiran -i tidak dibuang: tanpa kamus terlalu sering merupakan bagian kata
+	// dasar (kopi, lari, beli)
+	for _, suffix := range []string{"kan", "an"} {
+		if stem, ok := trimSuffix(word, suffix, minStemLength); ok {
+			word = stem
+			break
+		}
+	}
+	return stripIndonesianPrefix(word)
+}
+
+// indonesianPrefixes adalah awalan sederhana yang dibuang apa adanya, dari yang
+// terpanjang, beserta panjang minimum sisa katanya
+var indonesianPrefixes = []struct {
+	prefix    string
+	minLength int
+}{
+	{"ber", minStemLength}, {"ter", minStemLength}, {"per", minStemLength},
+	{"di", minStemLength}, {"ke", 5}, {"se", 5},
+}
+
+func stripIndonesianPrefix(word string) string {
+	for _, prefix := range []string{"me", "pe"} {
+		if !strings.HasPrefix(word, prefix) || len(word) < len(prefix)+minStemLength {
+			continue
+		}
+		rest := word[len(prefix):]
+		stem := ""
+		switch {
+		case strings.HasPrefix(rest, "ny") && isVowel(rest[2]):
+			// menyapu -> sapu
+			stem = "s" + rest[2:]
+		case strings.HasPrefix(rest, "ng") && len(rest) > 2:
+			// mengambil -> ambil, menggoreng -> goreng
+			stem = rest[2:]
+		case rest[0] == 'm' && isVowel(rest[1]):
+			// memukul -> pukul
+			stem = "p" + rest[1:]
+		case rest[0] == 'm' && strings.IndexByte("bfpv", rest[1]) >= 0:
+			// membeli -> beli
+			stem = rest[1:]
+		case rest[0] == 'n' && isVowel(rest[1]):
+			// menulis -> tulis
+			stem = "t" + rest[1:]
+		case rest[0] == 'n' && strings.IndexByte("cdjtsz", rest[1]) >= 0:
+			// mencuci -> cuci
+			stem = rest[1:]
+		case strings.IndexByte("lrwy", rest[0]) >= 0 && isVowel(rest[1]):
+			// melihat -> lihat; permen bukan pe-rmen
+			stem = rest
+		}
+		// pensil tidak dipotong menjadi sil
+		if utf8.RuneCountInString(stem) >= minStemLength {
+			return stem
+		}
+	}
+
+	for _, p := range indonesianPrefixes {
+		if strings.HasPrefix(word, p.prefix) && utf8.RuneCountInString(word)-len(p.prefix) >= p.minLength {
+			return word[len(p.prefix):]
+		}
+	}
+	return word
+}
+
+// stemEnglish membuang akhiran bahasa Inggris yang umum di nama produk
+// (running -> run, shoes -> shoe, batteries -> battery)
+func stemEnglish(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss") || strings.HasSuffix(word, "us") || strings.HasSuffix(word, "is"):
+		return word
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		if stem, ok := trimSuffix(word, suffix, minEnglishStemLength); ok {
+			// running -> runn -> run
+			if n := len(stem); n >= 2 && stem[n-1] == stem[n-2] && !isVowel(stem[n-1]) && strings.IndexByte("lsz", stem[n-1]) < 0 {
+				stem = stem[:n-1]
+			}
+			return stem
+		}
+	}
+	for _, suffix := range []string{"ches", "shes", "xes", "zes"} {
+		if strings.HasSuffix(word, suffix) {
+			return strings.TrimSuffix(word, "es")
+		}
+	}
+	if stem, ok := trimSuffix(word, "ly", minEnglishStemLength); ok {
+		return stem
+	}
+	if stem, ok := trimSuffix(word, "s", minEnglishStemLength); ok {
+		return stem
+	}
+	return word
+}
+
+// maxEdits adalah jumlah salah ketik yang ditoleransi menurut panjang kata;
+// kata pendek harus cocok persis atau sebagai awalan
+func maxEdits(word string) int {
+	switch n := utf8.RuneCountInString(word); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance menghitung jarak Damerau-Levenshtein (optimal string alignment)
+// dan berhenti lebih awal setelah melewati limit
+func editDistance(a string, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || -d > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		best := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			best = min(best, curr[j])
+		}
+		if best > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
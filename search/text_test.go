@@ -0,0 +1,81 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStemIndonesian(t *testing.T) {
+	for _, tc := range []struct {
+		word string
+		want string
+	}{
+		{"menulis", "tulis"},
+		{"memukul", "pukul"},
+		{"membeli", "beli"},
+		{"mengambil", "ambil"},
+		{"menggoreng", "goreng"},
+		{"menyapu", "sapu"},
+		{"mencuci", "cuci"},
+		{"melihat", "lihat"},
+		{"berlari", "lari"},
+		{"dibeli", "beli"},
+		{"sepatunya", "sepatu"},
+		{"mainan", "main"},
+		// Sisa kata dasar kurang dari empat huruf: tidak dipotong
+		{"pensil", "pensil"},
+		{"permen", "permen"},
+		{"tasnya", "tasnya"},
+	} {
+		if got := stemIndonesian(tc.word); got != tc.want {
+			t.Errorf("stemIndonesian(%q) = %q, want %q", tc.word, got, tc.want)
+		}
+	}
+}
+
+func TestLooksIndonesian(t *testing.T) {
+	for _, tc := range []struct {
+		word string
+		want bool
+	}{
+		{"sepatu", true},
+		{"menyapu", true},
+		{"mencuci", true},
+		{"pensil", true},
+		{"sayur", true},
+		{"memory", false},
+		{"keyboard", false},
+		{"black", false},
+		{"iphone", false},
+		{"sneakers", true},
+		{"book", false},
+		{"xiaomi", false},
+		{"ps5", false},
+	} {
+		if got := looksIndonesian(tc.word); got != tc.want {
+			t.Errorf("looksIndonesian(%q) = %v, want %v", tc.word, got, tc.want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	for _, tc := range []struct {
+		word string
+		want []string
+	}{
+		// Kata Inggris dan serapan tidak dipotong stemmer Indonesia
+		{"memory", []string{"memory"}},
+		{"pensil", []string{"pensil"}},
+		{"keyboard", []string{"keyboard"}},
+		{"memories", []string{"memories", "memory"}},
+		{"keyboards", []string{"keyboards", "keyboard"}},
+		{"running", []string{"running", "run"}},
+		{"bags", []string{"bags", "bag"}},
+		{"sepatunya", []string{"sepatunya", "sepatu"}},
+		{"menulis", []string{"menulis", "tulis"}},
+	} {
+		if got := variants(tc.word); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("variants(%q) = %q, want %q", tc.word, got, tc.want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package validators
 
-import "github.com/go-playground/validator/v10"
+import (
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
 
 var Validate = validator.New()
 
@@ -27,12 +31,29 @@ type UpdatePasswordInput struct {
     NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
+// DeleteAccountInput mengonfirmasi penghapusan akun. Password wajib untuk akun
+// yang punya password; akun yang hanya login lewat OIDC boleh mengosongkannya.
+type DeleteAccountInput struct {
+    Password string `json:"password"`
+}
+
+type ForgotPasswordInput struct {
+    Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput memakai aturan new_password yang sama dengan UpdatePasswordInput
+type ResetPasswordInput struct {
+    Token       string `json:"token" validate:"required"`
+    NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
 type AddProductInput struct {
     ProductName string `json:"productName" validate:"required"`
     BrandName   string `json:"brandName" validate:"required"`
     Price       int    `json:"price" validate:"required"`
     Quantity    int    `json:"quantity" validate:"required"`
-    Category    string  `json:"category" validate:"required"`
+    CategoryID  uint   `json:"category_id" validate:"required_without=Category"`
+    Category    string `json:"category" validate:"required_without=CategoryID"` // nama atau slug, untuk klien lama
 }
 
 // EditProductInput represents the input data for editing an existing product
@@ -41,7 +62,8 @@ type EditProductInput struct {
     BrandName   string  `json:"brandName" validate:"required,min=2,max=100"`
     Price       float64 `json:"price" validate:"required,gt=0"`
     Quantity    int     `json:"quantity"` // Tanpa validasi min=0
-    Category    string  `json:"category" validate:"required"`
+    CategoryID  uint    `json:"category_id" validate:"required_without=Category"`
+    Category    string  `json:"category" validate:"required_without=CategoryID"` // nama atau slug, untuk klien lama
 }
 
 type AddToCartInput struct {
@@ -53,10 +75,128 @@ type UpdateCartItemInput struct {
 	Quantity int `json:"quantity" validate:"required,min=1"`
 }
 
+// AddressInput membuat atau mengubah alamat di buku alamat customer. Wilayah
+// ditentukan oleh village_id (lihat /api/regions); kode provinsi, kota dan
+// kecamatan opsional tetapi jika dikirim harus cocok. Kode pos kosong diisi dari
+// data kelurahan.
+type AddressInput struct {
+	Label         string `json:"label" validate:"max=50"`
+	RecipientName string `json:"recipient_name" validate:"required,max=100"`
+	Phone         string `json:"phone" validate:"required,max=30"`
+	Street        string `json:"street" validate:"required,max=255"`
+	ProvinceID    string `json:"province_id" validate:"max=13"`
+	CityID        string `json:"city_id" validate:"max=13"`
+	DistrictID    string `json:"district_id" validate:"max=13"`
+	VillageID     string `json:"village_id" validate:"required,max=13"`
+	PostalCode    string `json:"postal_code" validate:"omitempty,numeric,len=5"`
+	Notes         string `json:"notes" validate:"max=255"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// CreateInvoiceInput memilih alamat pengiriman; kosong berarti alamat default
+type CreateInvoiceInput struct {
+	AddressID uint `json:"address_id"`
+}
+
 type OperatorLoginInput struct {
 	OperatorID string `json:"operator_id" validate:"required"`
+	Password   string `json:"password" validate:"required"`
 }
 
 type AdminLoginInput struct {
-    AdminID string `json:"admin_id" validate:"required"`
+    AdminID  string `json:"admin_id" validate:"required"`
+    Password string `json:"password" validate:"required"`
+}
+
+type RevokeAccountSessionsInput struct {
+	Role      string `json:"role" validate:"required,oneof=user operator admin"`
+	SubjectID uint   `json:"subject_id" validate:"required"`
+}
+
+type CreateOperatorInput struct {
+	OperatorID string `json:"operator_id" validate:"required"`
+	Name       string `json:"name" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Phone      string `json:"phone"`
+	Password   string `json:"password" validate:"required,min=8"`
+	Roles      []string `json:"roles"` // kosong berarti role bawaan "operator"
+}
+
+type CreateAdminInput struct {
+	AdminID  string `json:"admin_id" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password" validate:"required,min=8"`
+	Roles    []string `json:"roles"` // kosong berarti role bawaan "admin"
+}
+
+// UpdateStaffInput mengubah data operator atau admin. Password bersifat opsional;
+// jika diisi, password direset dan harus diganti saat login berikutnya.
+type UpdateStaffInput struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password" validate:"omitempty,min=8"`
+}
+
+// RoleInput membuat atau mengubah role beserta daftar permission-nya
+type RoleInput struct {
+	Name        string   `json:"name" validate:"required,max=64"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" validate:"required,min=1"`
+}
+
+// CategoryInput membuat atau mengubah kategori produk. Slug dibuat dari nama jika
+// kosong; Active kosong berarti aktif saat dibuat dan tidak berubah saat diedit.
+type CategoryInput struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	Slug      string `json:"slug" validate:"omitempty,max=120"`
+	ParentID  *uint  `json:"parent_id"`
+	SortOrder int    `json:"sort_order"`
+	Active    *bool  `json:"active"`
+}
+
+// AssignRolesInput mengganti seluruh role milik operator atau admin
+type AssignRolesInput struct {
+	Roles []string `json:"roles" validate:"required,min=1"`
+}
+
+// UnlockLoginInput menghapus lockout login untuk satu identitas: email untuk
+// user, operator_id untuk operator, admin_id untuk admin. IP (opsional) ikut
+// dibuka jika user juga terkena lockout per IP.
+type UnlockLoginInput struct {
+	Role       string `json:"role" validate:"required,oneof=user operator admin"`
+	Identifier string `json:"identifier" validate:"required"`
+	IP         string `json:"ip" validate:"omitempty,ip"`
+}
+
+// MFACodeInput berisi kode TOTP 6 digit atau salah satu recovery code
+type MFACodeInput struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// MFALoginInput adalah langkah kedua login staff yang memakai MFA
+type MFALoginInput struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MFASettingInput struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+// APIKeyInput membuat API key untuk integrasi mesin. Permissions adalah scope
+// eksplisit key; RateLimit dalam request per menit (default 60).
+type APIKeyInput struct {
+	Name        string     `json:"name" validate:"required,max=100"`
+	Permissions []string   `json:"permissions" validate:"required,min=1"`
+	RateLimit   int        `json:"rate_limit" validate:"omitempty,min=1,max=10000"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+}
+
+// RotateSigningKeyInput menentukan apakah kunci JWT yang aktif langsung dicabut,
+// misalnya jika kuncinya bocor
+type RotateSigningKeyInput struct {
+	RevokeCurrent bool `json:"revoke_current"`
 }